@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Article extraction follows the approach of Arc90's readability: page chrome
+// is stripped, paragraphs vote for the element containing them by how much
+// text they hold, and the best scoring element is taken as the article body
+// together with any siblings that look like part of it.
+
+var (
+	unlikelyRE = classRE(`ads?|adverts?|advertisement|banners?|breadcrumbs?|combx|comments?|community|cookies?|disqus|footer|header|menus?|meta|modal|nav|navbar|navigation|newsletter|pager|pagination|popup|promos?|related|remark|share|sharing|shoutbox|sidebar|social|sponsor|sponsored|subscribe|tags|tools?|toolbar`)
+	maybeRE    = classRE(`article|body|column|content|main|post|shadow|story`)
+	positiveRE = classRE(`article|body|content|entry|hentry|main|page|post|story|text`)
+	negativeRE = classRE(`comments?|com|contact|footer|footnotes?|masthead|media|meta|outbrain|promos?|related|scroll|share|sharing|shoutbox|sidebar|sponsor|sponsored|shopping|tags|tools?|widgets?`)
+)
+
+// classRE matches any of words as a whole token of a class or id, split at
+// spaces, dashes and underscores, so nav matches site-nav but not canvas.
+func classRE(words string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[\s_-])(` + words + `)($|[\s_-])`)
+}
+
+// dropTags never hold article text.
+var dropTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Form: true,
+	atom.Iframe: true, atom.Svg: true, atom.Button: true, atom.Input: true,
+	atom.Select: true, atom.Textarea: true, atom.Object: true, atom.Embed: true,
+}
+
+// blockTags start a new paragraph when the article is turned into text.
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Blockquote: true, atom.Pre: true, atom.Ul: true,
+	atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Tr: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Figcaption: true, atom.Hr: true,
+}
+
+// extractArticle finds the main body of an html page and returns it as plain
+// text, paragraphs separated by blank lines.
+func extractArticle(page []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", fmt.Errorf("error: could not parse page -> %w", err)
+	}
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		return "", fmt.Errorf("error: page has no body")
+	}
+	stripChrome(body)
+	top := bestCandidate(body)
+	if top == nil {
+		top = body
+	}
+	text := strings.TrimSpace(articleText(top))
+	if text == "" {
+		return "", fmt.Errorf("error: found no article text on the page")
+	}
+	return text, nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// stripChrome removes elements that never hold the article, and those whose
+// class or id suggest navigation, ads or comments.
+func stripChrome(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || c.Type == html.ElementNode && isChrome(c) {
+			n.RemoveChild(c)
+		} else {
+			stripChrome(c)
+		}
+		c = next
+	}
+}
+
+func isChrome(n *html.Node) bool {
+	if dropTags[n.DataAtom] {
+		return true
+	}
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	if attr(n, "role") == "navigation" || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyRE.MatchString(names) && !maybeRE.MatchString(names)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// bestCandidate scores the parents of every paragraph and returns the element
+// most likely to be the article.
+func bestCandidate(body *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return
+		}
+		text := innerText(n)
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		if parent := n.Parent; parent != nil && parent.Type == html.ElementNode {
+			if _, ok := scores[parent]; !ok {
+				scores[parent] = baseScore(parent)
+			}
+			scores[parent] += score
+			if grand := parent.Parent; grand != nil && grand.Type == html.ElementNode {
+				if _, ok := scores[grand]; !ok {
+					scores[grand] = baseScore(grand)
+				}
+				scores[grand] += score / 2
+			}
+		}
+	}
+	walk(body)
+	var top *html.Node
+	best := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		scores[n] = score
+		if score > best {
+			top, best = n, score
+		}
+	}
+	if top == nil {
+		return nil
+	}
+	return withSiblings(top, best, scores)
+}
+
+// baseScore weighs an element by its tag and by what its class and id say.
+func baseScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	if positiveRE.MatchString(names) {
+		score += 25
+	}
+	if negativeRE.MatchString(names) {
+		score -= 25
+	}
+	return score
+}
+
+// linkDensity is the share of an element's text that is inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			links += len(innerText(c))
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+// withSiblings gathers the top candidate and any siblings scoring close to it,
+// or paragraphs of prose, under a new node in their original order.
+func withSiblings(top *html.Node, best float64, scores map[*html.Node]float64) *html.Node {
+	if top.Parent == nil {
+		return top
+	}
+	threshold := math.Max(10, best*0.2)
+	article := &html.Node{Type: html.ElementNode, DataAtom: atom.Div, Data: "div"}
+	for sib := top.Parent.FirstChild; sib != nil; {
+		next := sib.NextSibling
+		keep := sib == top
+		if !keep && sib.Type == html.ElementNode {
+			if score, ok := scores[sib]; ok && score >= threshold {
+				keep = true
+			} else if sib.DataAtom == atom.P {
+				text := innerText(sib)
+				density := linkDensity(sib)
+				keep = len(text) > 80 && density < 0.25 ||
+					len(text) > 0 && density == 0 && strings.HasSuffix(strings.TrimSpace(text), ".")
+			}
+		}
+		if keep {
+			top.Parent.RemoveChild(sib)
+			article.AppendChild(sib)
+		}
+		sib = next
+	}
+	return article
+}
+
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return normalizeSpaces(b.String())
+}
+
+// articleText flattens the article into paragraphs, marking headings, list
+// items and quotes, and keeping preformatted text as it is.
+func articleText(n *html.Node) string {
+	var out strings.Builder
+	var para strings.Builder
+	flush := func(prefix string) {
+		text := normalizeSpaces(para.String())
+		para.Reset()
+		if text == "" {
+			return
+		}
+		out.WriteString(prefix + text + "\n\n")
+	}
+	var walk func(*html.Node, string)
+	walk = func(c *html.Node, prefix string) {
+		switch c.Type {
+		case html.TextNode:
+			para.WriteString(c.Data)
+			return
+		case html.ElementNode:
+		default:
+			for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+				walk(cc, prefix)
+			}
+			return
+		}
+		switch c.DataAtom {
+		case atom.Img:
+			if alt := strings.TrimSpace(attr(c, "alt")); alt != "" {
+				para.WriteString(" [" + alt + "] ")
+			}
+			return
+		case atom.Br:
+			para.WriteString(" ")
+			return
+		case atom.Pre:
+			flush(prefix)
+			var pre strings.Builder
+			var raw func(*html.Node)
+			raw = func(cc *html.Node) {
+				if cc.Type == html.TextNode {
+					pre.WriteString(cc.Data)
+				}
+				for ccc := cc.FirstChild; ccc != nil; ccc = ccc.NextSibling {
+					raw(ccc)
+				}
+			}
+			raw(c)
+			if text := strings.Trim(pre.String(), "\n"); text != "" {
+				out.WriteString(preMarker + strings.ReplaceAll(text, "\n", "\n"+preMarker) + "\n\n")
+			}
+			return
+		}
+		if !blockTags[c.DataAtom] {
+			for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+				walk(cc, prefix)
+			}
+			return
+		}
+		flush(prefix)
+		inner := prefix
+		switch c.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			inner = prefix + "## "
+		case atom.Li:
+			inner = prefix + "• "
+		case atom.Blockquote:
+			inner = prefix + "│ "
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc, inner)
+		}
+		flush(inner)
+	}
+	walk(n, "")
+	return out.String()
+}
+
+// preMarker starts each line of preformatted text so wrapText leaves it be.
+const preMarker = "    "
+
+// wrapText wraps each paragraph of text to width columns, continuing list
+// items and quotes under their marker. Preformatted lines are left as is.
+func wrapText(text string, width int) string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" || strings.HasPrefix(line, preMarker) {
+			out = append(out, line)
+			continue
+		}
+		indent := ""
+		for _, marker := range []string{"│ ", "• "} {
+			for strings.HasPrefix(line[len(indent):], marker) {
+				indent += marker
+			}
+		}
+		hang := strings.ReplaceAll(indent, "• ", "  ")
+		current := indent
+		for _, word := range strings.Fields(line[len(indent):]) {
+			if len([]rune(current))+len([]rune(word)) > width && strings.TrimSpace(current) != strings.TrimSpace(indent) {
+				out = append(out, strings.TrimRight(current, " "))
+				current = hang
+			}
+			current += word + " "
+		}
+		out = append(out, strings.TrimRight(current, " "))
+	}
+	return strings.Join(out, "\n")
+}
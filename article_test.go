@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestIsChrome(t *testing.T) {
+	tests := []struct {
+		class string
+		id    string
+		want  bool
+	}{
+		{class: "nav", want: true},
+		{class: "site-nav", want: true},
+		{class: "top_menu", want: true},
+		{class: "ads", want: true},
+		{class: "ad-slot", want: true},
+		{id: "comments", want: true},
+		{class: "Sidebar widget", want: true},
+		{class: "meta", want: true},
+		{class: "header", want: true},
+		{class: "canvas"},
+		{class: "heads"},
+		{class: "loads"},
+		{class: "metadata"},
+		{class: "tooltip"},
+		{class: "subheader"},
+		{class: "navigator"},
+		{class: "comment-body"},
+		{class: "post-meta"},
+		{class: ""},
+	}
+	for _, tt := range tests {
+		n := &html.Node{Type: html.ElementNode, DataAtom: atom.Div, Data: "div"}
+		if tt.class != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "class", Val: tt.class})
+		}
+		if tt.id != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "id", Val: tt.id})
+		}
+		if got := isChrome(n); got != tt.want {
+			t.Errorf("isChrome(class=%q id=%q) = %v, want %v", tt.class, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestExtractArticle(t *testing.T) {
+	para := "This paragraph is long enough, with commas, to be scored as part of the article text."
+	tests := []struct {
+		name    string
+		page    string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{
+			name: "article with chrome",
+			page: `<html><body>
+				<nav>Home About Contact</nav>
+				<div class="ads">Buy things now</div>
+				<div class="content"><h1>Title</h1><p>` + para + `</p><p>Second: ` + para + `</p></div>
+				<div id="comments"><p>First comment, ` + para + `</p></div>
+				<footer>Copyright</footer>
+				</body></html>`,
+			want:    []string{"## Title", para, "Second: " + para},
+			notWant: []string{"Home About", "Buy things", "First comment", "Copyright"},
+		},
+		{
+			name: "class names that only contain chrome words",
+			page: `<html><body><div class="story">
+				<div class="subheader"><p>Subheader: ` + para + `</p></div>
+				<div class="metadata"><p>Metadata: ` + para + `</p></div>
+				<div class="tooltip"><p>Tooltip: ` + para + `</p></div>
+				<div class="canvas"><p>Canvas: ` + para + `</p></div>
+				<div class="heads"><p>Heads: ` + para + `</p></div>
+				</div></body></html>`,
+			want: []string{"Subheader: ", "Metadata: ", "Tooltip: ", "Canvas: ", "Heads: "},
+		},
+		{
+			name: "lists quotes and preformatted text",
+			page: `<html><body><article><p>` + para + `</p>
+				<ul><li>one</li><li>two</li></ul>
+				<blockquote>quoted words</blockquote>
+				<pre>line one
+  line two</pre></article></body></html>`,
+			want: []string{"• one\n\n• two", "│ quoted words", preMarker + "line one\n" + preMarker + "  line two"},
+		},
+		{
+			name:    "no text",
+			page:    `<html><body><nav>Home</nav><script>var x</script></body></html>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractArticle([]byte(tt.page))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extractArticle() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractArticle() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("extractArticle() = %q, missing %q", got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("extractArticle() = %q, should not contain %q", got, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{
+			name:  "short line",
+			text:  "one two",
+			width: 20,
+			want:  "one two",
+		},
+		{
+			name:  "wraps at width",
+			text:  "one two three four",
+			width: 9,
+			want:  "one two\nthree\nfour",
+		},
+		{
+			name:  "long word on its own line",
+			text:  "a extraordinarily b",
+			width: 5,
+			want:  "a\nextraordinarily\nb",
+		},
+		{
+			name:  "list item hangs under its marker",
+			text:  "• one two three",
+			width: 9,
+			want:  "• one two\n  three",
+		},
+		{
+			name:  "quote keeps its marker",
+			text:  "│ one two three",
+			width: 9,
+			want:  "│ one two\n│ three",
+		},
+		{
+			name:  "preformatted left as is",
+			text:  preMarker + "one two three four",
+			width: 9,
+			want:  preMarker + "one two three four",
+		},
+		{
+			name:  "paragraphs kept apart",
+			text:  "one two\n\nthree",
+			width: 20,
+			want:  "one two\n\nthree",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.text, tt.width); got != tt.want {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/jdfincher/gator/internal/config"
+)
+
+const (
+	backoffBase       = time.Minute
+	defaultMaxBackoff = 6 * time.Hour
+)
+
+// backoffDelay returns how long to leave a feed alone after it has failed
+// failures times in a row. The delay doubles from backoffBase up to max and is
+// jittered across its upper half so feeds on a downed host don't retry in lockstep.
+func backoffDelay(failures int32, max time.Duration) time.Duration {
+	d := backoffBase
+	for i := int32(1); i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+func maxBackoff(cfg *config.Config) time.Duration {
+	if cfg.Fetch.MaxBackoff.Duration > 0 {
+		return cfg.Fetch.MaxBackoff.Duration
+	}
+	return defaultMaxBackoff
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		max      time.Duration
+		want     time.Duration // delay before jitter
+	}{
+		{"first failure", 1, defaultMaxBackoff, backoffBase},
+		{"second failure doubles", 2, defaultMaxBackoff, 2 * backoffBase},
+		{"fifth failure", 5, defaultMaxBackoff, 16 * backoffBase},
+		{"capped at max", 20, defaultMaxBackoff, defaultMaxBackoff},
+		{"max below base", 1, 10 * time.Second, 10 * time.Second},
+		{"huge failure count", 1 << 30, time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 50 {
+				got := backoffDelay(tt.failures, tt.max)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("backoffDelay(%d, %v) = %v, want between %v and %v", tt.failures, tt.max, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// encodeCursor packs the sort position of the last post on a page so the next
+// page starts right after it, however many posts were added in the meantime.
+func encodeCursor(at time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("error: invalid cursor '%v'", cursor)
+	}
+	at, id, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("error: invalid cursor '%v'", cursor)
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("error: invalid cursor '%v'", cursor)
+	}
+	postID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("error: invalid cursor '%v'", cursor)
+	}
+	return t, postID, nil
+}
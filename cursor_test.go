@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("3f2a9c1e-5b7d-4e8f-9a0b-1c2d3e4f5a6b")
+	tests := []time.Time{
+		time.Date(2026, 10, 18, 9, 15, 0, 0, time.UTC),
+		time.Date(2026, 10, 18, 9, 15, 0, 123456789, time.UTC),
+		time.Date(1999, 12, 31, 23, 59, 59, 0, time.FixedZone("", -5*3600)),
+	}
+	for _, at := range tests {
+		gotAt, gotID, err := decodeCursor(encodeCursor(at, id))
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%v)) error = %v", at, err)
+		}
+		if !gotAt.Equal(at) || gotID != id {
+			t.Errorf("cursor round trip = %v, %v, want %v, %v", gotAt, gotID, at, id)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"no separator", encode("2026-10-18T09:15:00Z")},
+		{"bad time", encode("yesterday|3f2a9c1e-5b7d-4e8f-9a0b-1c2d3e4f5a6b")},
+		{"bad id", encode("2026-10-18T09:15:00Z|3f2a9c1e")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("decodeCursor(%q) accepted an invalid cursor", tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startAggServers serves /metrics and the /healthz and /readyz probes in the
+// background, sharing one listener when both are given the same address.
+func startAggServers(s *state, metricsAddr, healthAddr string, interval time.Duration) error {
+	muxes := make(map[string]*http.ServeMux)
+	route := func(addr string) *http.ServeMux {
+		if _, ok := muxes[addr]; !ok {
+			muxes[addr] = http.NewServeMux()
+		}
+		return muxes[addr]
+	}
+	if metricsAddr != "" {
+		route(metricsAddr).Handle("/metrics", s.metrics.handler())
+	}
+	if healthAddr != "" {
+		mux := route(healthAddr)
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, "ok")
+		})
+		mux.HandleFunc("/readyz", readyz(s, interval))
+	}
+	for addr, mux := range muxes {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("error: could not listen on %v -> %w", addr, err)
+		}
+		s.log.Info("serving http", "addr", listener.Addr().String())
+		go func() {
+			if err := http.Serve(listener, mux); err != nil {
+				s.log.Error("http server stopped", "addr", addr, "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// readyz reports ready while the database answers a ping and a fetch cycle
+// has completed within two intervals.
+func readyz(s *state, interval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := s.sqlDB.PingContext(ctx); err != nil {
+			http.Error(w, "database unreachable: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		since, ok := s.metrics.sinceLastCycle()
+		if !ok {
+			http.Error(w, "no fetch cycle has completed yet", http.StatusServiceUnavailable)
+			return
+		}
+		if since > 2*interval {
+			http.Error(w, fmt.Sprintf("no completed fetch cycle in %v", since.Round(time.Second)), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// runningPid describes the agg holding the pidfile at path for an error
+// message, by its pid when the file has one.
+func runningPid(path string) error {
+	data, _ := os.ReadFile(path)
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+		return fmt.Errorf("error: agg is already running with pid %d, holding %v", pid, path)
+	}
+	return fmt.Errorf("error: agg is already running, holding %v", path)
+}
+
+func writePid(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("error: could not write pidfile -> %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("error: could not write pidfile -> %w", err)
+	}
+	return nil
+}
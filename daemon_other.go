@@ -0,0 +1,33 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// lockPidfile creates path exclusively and writes our pid to it, so a second
+// agg on the same host refuses to start. Without flock the file is the lock,
+// it is removed by the returned func but left behind if agg is killed, and
+// must then be removed by hand.
+func lockPidfile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w, remove it if that agg is no longer running", runningPid(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error: could not create pidfile -> %w", err)
+	}
+	if err := writePid(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	release := func() {
+		f.Close()
+		os.Remove(path)
+	}
+	return release, nil
+}
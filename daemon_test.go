@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLockPidfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agg.pid")
+	release, err := lockPidfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("pidfile holds %q, want our pid %d", got, os.Getpid())
+	}
+	if _, err := lockPidfile(path); err == nil || !strings.Contains(err.Error(), "already running with pid") {
+		t.Errorf("second lockPidfile() error = %v, want already running", err)
+	}
+	release()
+	release, err = lockPidfile(path)
+	if err != nil {
+		t.Fatalf("lockPidfile() after release error = %v", err)
+	}
+	release()
+}
+
+func TestSinceLastCycle(t *testing.T) {
+	m := newAggMetrics()
+	if _, ok := m.sinceLastCycle(); ok {
+		t.Error("sinceLastCycle() reports a completed cycle before any has run")
+	}
+	m.cycleCompleted(false)
+	if since, ok := m.sinceLastCycle(); !ok || since < 0 {
+		t.Errorf("sinceLastCycle() = %v, %v after a cycle, want a small duration and true", since, ok)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockPidfile takes an exclusive lock on path and writes our pid to it, so a
+// second agg on the same host refuses to start. The lock is released by the
+// returned func or when the process exits, however it exits. The file itself
+// is left in place, removing it while another agg waits to lock it would let
+// a third lock a new file at the same path.
+func lockPidfile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not open pidfile -> %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, runningPid(path)
+		}
+		return nil, fmt.Errorf("error: could not lock pidfile -> %w", err)
+	}
+	if err := writePid(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	release := func() {
+		f.Truncate(0)
+		f.Close()
+	}
+	return release, nil
+}
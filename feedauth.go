@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+// envPrefix marks a stored credential as a reference to an environment
+// variable, read when the feed is fetched, rather than the secret itself.
+const envPrefix = "env:"
+
+// feedCredentials are the per feed auth and extra headers sent with each request.
+// They were set for the feed's url, so they are only sent to Host, the host of
+// that url. A feed with credentials is never moved to another host by agg.
+type feedCredentials struct {
+	Host     string
+	AuthType string // "basic", "bearer" or empty for none
+	Username string
+	Secret   string
+	Headers  []database.FeedHeader
+}
+
+func handlerSetAuth(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("error: use 'setauth url basic username password', 'setauth url bearer token' or 'setauth url none'")
+	}
+	auth := database.SetFeedAuthParams{
+		UpdatedAt: time.Now(),
+		Url:       cmd.args[0],
+		UserID:    user.ID,
+	}
+	var secret string
+	switch kind := strings.ToLower(cmd.args[1]); kind {
+	case "basic":
+		if len(cmd.args) < 4 {
+			return fmt.Errorf("error: basic auth requires a username and password, use 'setauth url basic username password'")
+		}
+		auth.AuthType = sql.NullString{String: kind, Valid: true}
+		auth.AuthUsername = sql.NullString{String: cmd.args[2], Valid: true}
+		secret = cmd.args[3]
+	case "bearer":
+		if len(cmd.args) < 3 {
+			return fmt.Errorf("error: bearer auth requires a token, use 'setauth url bearer token'")
+		}
+		auth.AuthType = sql.NullString{String: kind, Valid: true}
+		secret = cmd.args[2]
+	case "none":
+	default:
+		return fmt.Errorf("error: unknown auth type '%v', use basic, bearer or none", cmd.args[1])
+	}
+	if secret != "" {
+		auth.AuthSecret = sql.NullString{String: secret, Valid: true}
+	}
+	n, err := s.db.SetFeedAuth(context.Background(), auth)
+	if err != nil {
+		return fmt.Errorf("error: could not save feed auth -> %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error: no feed with url %v added by %v", cmd.args[0], user.Name)
+	}
+	if !auth.AuthType.Valid {
+		fmt.Printf("»»»» auth removed from %v\n", cmd.args[0])
+		return nil
+	}
+	fmt.Printf("»»»» %v auth set on %v\n", auth.AuthType.String, cmd.args[0])
+	warnPlaintext(secret)
+	return nil
+}
+
+func handlerSetHeader(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 3 {
+		return fmt.Errorf("error: use 'setheader url name value' to send a header with a feed's requests")
+	}
+	feedID, err := ownedFeedID(s, cmd.args[0], user)
+	if err != nil {
+		return err
+	}
+	header := database.SetFeedHeaderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feedID,
+		Name:      http.CanonicalHeaderKey(cmd.args[1]),
+		Value:     cmd.args[2],
+	}
+	if err := s.db.SetFeedHeader(context.Background(), header); err != nil {
+		return fmt.Errorf("error: could not save feed header -> %w", err)
+	}
+	fmt.Printf("»»»» header %v set on %v\n", header.Name, cmd.args[0])
+	warnPlaintext(header.Value)
+	return nil
+}
+
+func handlerUnsetHeader(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("error: use 'unsetheader url name' to stop sending a header")
+	}
+	feedID, err := ownedFeedID(s, cmd.args[0], user)
+	if err != nil {
+		return err
+	}
+	header := database.DeleteFeedHeaderParams{
+		FeedID: feedID,
+		Name:   http.CanonicalHeaderKey(cmd.args[1]),
+	}
+	n, err := s.db.DeleteFeedHeader(context.Background(), header)
+	if err != nil {
+		return fmt.Errorf("error: could not remove feed header -> %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error: feed %v has no header %v", cmd.args[0], header.Name)
+	}
+	fmt.Printf("»»»» header %v removed from %v\n", header.Name, cmd.args[0])
+	return nil
+}
+
+// ownedFeedID looks up a feed by url, only credentials for feeds the user added can be changed.
+func ownedFeedID(s *state, url string, user database.User) (uuid.UUID, error) {
+	owned := database.GetFeedIDForUserParams{
+		Url:    url,
+		UserID: user.ID,
+	}
+	feedID, err := s.db.GetFeedIDForUser(context.Background(), owned)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error: no feed with url %v added by %v -> %w", url, user.Name, err)
+	}
+	return feedID, nil
+}
+
+func warnPlaintext(value string) {
+	if value != "" && !strings.HasPrefix(value, envPrefix) {
+		fmt.Printf("»»»» note: stored in plaintext, use '%vVAR_NAME' to read it from the environment instead\n", envPrefix)
+	}
+}
+
+// loadFeedCredentials gathers the auth and headers to send when fetching feed.
+func loadFeedCredentials(s *state, feed database.GetNextFeedToFetchRow) (feedCredentials, error) {
+	headers, err := s.db.GetFeedHeaders(context.Background(), feed.ID)
+	if err != nil {
+		return feedCredentials{}, fmt.Errorf("error: could not fetch feed headers -> %w", err)
+	}
+	creds := feedCredentials{
+		Host:     feedHost(feed.Url),
+		AuthType: feed.AuthType.String,
+		Username: feed.AuthUsername.String,
+		Secret:   feed.AuthSecret.String,
+		Headers:  headers,
+	}
+	return creds, nil
+}
+
+// empty reports whether there is nothing to send with the feed's requests.
+func (c feedCredentials) empty() bool {
+	return c.AuthType == "" && len(c.Headers) == 0
+}
+
+// apply sets the credentials on req, resolving any environment references.
+// Nothing is set when req is for a different host than the credentials'.
+func (c feedCredentials) apply(req *http.Request) error {
+	if c.empty() || !strings.EqualFold(req.URL.Hostname(), c.Host) {
+		return nil
+	}
+	for _, h := range c.Headers {
+		value, err := resolveSecret(h.Value)
+		if err != nil {
+			return fmt.Errorf("error: header %v -> %w", h.Name, err)
+		}
+		req.Header.Set(h.Name, value)
+	}
+	switch c.AuthType {
+	case "basic":
+		username, err := resolveSecret(c.Username)
+		if err != nil {
+			return fmt.Errorf("error: basic auth username -> %w", err)
+		}
+		password, err := resolveSecret(c.Secret)
+		if err != nil {
+			return fmt.Errorf("error: basic auth password -> %w", err)
+		}
+		req.SetBasicAuth(username, password)
+	case "bearer":
+		token, err := resolveSecret(c.Secret)
+		if err != nil {
+			return fmt.Errorf("error: bearer token -> %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+func resolveSecret(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, envPrefix)
+	if !ok {
+		return value, nil
+	}
+	secret, set := os.LookupEnv(name)
+	if !set {
+		return "", fmt.Errorf("environment variable %v is not set", name)
+	}
+	return secret, nil
+}
+
+// describeAuth summarises a feed's auth for listing with the secret masked.
+func describeAuth(feed database.Feed) string {
+	if feed.AuthUsername.Valid {
+		return fmt.Sprintf("%v %v:%v", feed.AuthType.String, feed.AuthUsername.String, maskSecret(feed.AuthSecret.String))
+	}
+	return fmt.Sprintf("%v %v", feed.AuthType.String, maskSecret(feed.AuthSecret.String))
+}
+
+// maskSecret hides a stored credential for display, environment references
+// are shown as is since they name the variable rather than hold the secret.
+func maskSecret(value string) string {
+	if strings.HasPrefix(value, envPrefix) {
+		return value
+	}
+	return "****"
+}
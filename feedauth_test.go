@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdfincher/gator/internal/config"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+func TestMaskSecret(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"hunter2", "****"},
+		{"", "****"},
+		{"env:FEED_TOKEN", "env:FEED_TOKEN"},
+		{"ENV:FEED_TOKEN", "****"},
+		{"Bearer env:FEED_TOKEN", "****"},
+	}
+	for _, tt := range tests {
+		if got := maskSecret(tt.value); got != tt.want {
+			t.Errorf("maskSecret(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("GATOR_TEST_SECRET", "s3cret")
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"env:GATOR_TEST_SECRET", "s3cret", false},
+		{"env:GATOR_TEST_UNSET", "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveSecret(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveSecret(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyCredentials(t *testing.T) {
+	creds := feedCredentials{
+		Host:     "feeds.example.com",
+		AuthType: "bearer",
+		Secret:   "token",
+		Headers:  []database.FeedHeader{{Name: "X-Api-Key", Value: "key"}},
+	}
+	tests := []struct {
+		name     string
+		url      string
+		wantAuth string
+		wantKey  string
+	}{
+		{"same host", "https://feeds.example.com/rss", "Bearer token", "key"},
+		{"host differs in case", "https://Feeds.Example.com/rss", "Bearer token", "key"},
+		{"other host", "https://evil.example.net/rss", "", ""},
+		{"sub domain", "https://a.feeds.example.com/rss", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := creds.apply(req); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+			}
+			if got := req.Header.Get("X-Api-Key"); got != tt.wantKey {
+				t.Errorf("X-Api-Key = %q, want %q", got, tt.wantKey)
+			}
+		})
+	}
+}
+
+// TestRedirectDropsCredentials follows a feed from 127.0.0.1 to localhost,
+// which net/http treats as another host.
+func TestRedirectDropsCredentials(t *testing.T) {
+	var got http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss><channel><title>t</title></channel></rss>`))
+	}))
+	defer target.Close()
+	moved := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, moved+"/feed", http.StatusMovedPermanently)
+	}))
+	defer origin.Close()
+
+	cfg := &config.Config{}
+	cfg.Fetch.HostMinDelay.Duration = time.Millisecond
+	f, err := newFetcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := feedCredentials{
+		Host:     feedHost(origin.URL),
+		AuthType: "basic",
+		Username: "user",
+		Secret:   "pass",
+		Headers: []database.FeedHeader{
+			{Name: "Private-Token", Value: "private"},
+			{Name: "X-Api-Key", Value: "key"},
+		},
+	}
+	if _, _, err := f.fetchFeed(context.Background(), origin.URL+"/feed", creds); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("redirect target was never requested")
+	}
+	for _, name := range []string{"Authorization", "Private-Token", "X-Api-Key"} {
+		if v := got.Get(name); v != "" {
+			t.Errorf("%v sent to another host after redirect: %q", name, v)
+		}
+	}
+	if got.Get("User-Agent") != defaultUserAgent {
+		t.Errorf("User-Agent = %q, want %q", got.Get("User-Agent"), defaultUserAgent)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/jdfincher/gator/internal/config"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultFetchTimeout = 10 * time.Second
+	defaultUserAgent    = "gator/1.0 (+https://github.com/jdfincher/gator)"
+	defaultMaxBodyBytes = 10 << 20
+	maxRedirects        = 10
+
+	// acceptEncoding is negotiated by gator rather than left to net/http, which
+	// only asks for gzip and hides how many bytes actually crossed the wire.
+	acceptEncoding = "gzip, deflate, br"
+)
+
+// fetcher is the long lived http client every feed is fetched through, so
+// connections are kept alive and reused between fetches.
+type fetcher struct {
+	client       *http.Client
+	userAgent    string
+	maxBodyBytes int64
+}
+
+// fetchInfo describes where a feed request ended up after following redirects.
+type fetchInfo struct {
+	FinalURL   string
+	Permanent  bool // every hop was a 301 or 308
+	StatusCode int
+	WireBytes  int64 // body bytes as sent, compressed or not
+	BodyBytes  int64 // body bytes after decompression
+}
+
+type fetchInfoKey struct{}
+
+// statusError is returned by fetchFeed when the server answers with a non 2xx status.
+type statusError struct {
+	StatusCode int
+	RetryAfter time.Duration // zero when the server sent no usable Retry-After
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("error: server responded %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// parseError is returned by fetchFeed when the response arrived but is not a
+// feed it can read.
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+func newFetcher(cfg *config.Config) (*fetcher, error) {
+	limiter := newHostLimiter(cfg)
+	tlsConfig, err := newTLSConfig(cfg.Fetch)
+	if err != nil {
+		return nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if cfg.Fetch.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Fetch.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid fetch proxy url -> %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	dial := dialer.DialContext
+	var guard *ssrfGuard
+	if cfg.Fetch.BlockPrivateAddresses {
+		guard, err = newSSRFGuard(cfg.Fetch, dialer)
+		if err != nil {
+			return nil, err
+		}
+		dial = guard.DialContext
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		DisableCompression:    true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   limiter.maxConns,
+		MaxConnsPerHost:       limiter.maxConns,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	timeout := defaultFetchTimeout
+	if cfg.Fetch.Timeout.Duration > 0 {
+		timeout = cfg.Fetch.Timeout.Duration
+	}
+	userAgent := defaultUserAgent
+	if cfg.Fetch.UserAgent != "" {
+		userAgent = cfg.Fetch.UserAgent
+	}
+	maxBodyBytes := int64(defaultMaxBodyBytes)
+	if cfg.Fetch.MaxBodyBytes > 0 {
+		maxBodyBytes = cfg.Fetch.MaxBodyBytes
+	}
+	var next http.RoundTripper = &politeTransport{next: transport, limiter: limiter}
+	if guard != nil {
+		next = &proxyGuard{next: next, proxy: proxy, guard: guard}
+	}
+	f := &fetcher{
+		client: &http.Client{
+			Transport:     next,
+			Timeout:       timeout,
+			CheckRedirect: checkRedirect,
+		},
+		userAgent:    userAgent,
+		maxBodyBytes: maxBodyBytes,
+	}
+	return f, nil
+}
+
+// ensureFetcher builds the fetcher from the fetch config the first time a
+// command needs it, so a bad fetch section only stops commands that fetch.
+func (s *state) ensureFetcher() error {
+	if s.fetcher != nil {
+		return nil
+	}
+	f, err := newFetcher(s.cfg)
+	if err != nil {
+		return err
+	}
+	s.fetcher = f
+	return nil
+}
+
+func newTLSConfig(fc config.FetchConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: fc.InsecureSkipVerify,
+	}
+	if fc.CAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(fc.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error: reading fetch ca_file -> %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("error: no certificates found in fetch ca_file %v", fc.CAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// checkRedirect follows up to maxRedirects hops, noting on the request's
+// fetchInfo whether every hop so far has been permanent. net/http copies every
+// header of the first request to each hop and only strips Authorization and
+// cookies when the host changes, so a hop to another host is stripped down to
+// the headers gator sets itself, keeping a feed's credentials and custom
+// headers on the host they were set for.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("error: stopped after %d redirects", maxRedirects)
+	}
+	if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+		for name := range req.Header {
+			if name != "User-Agent" && name != "Accept-Encoding" && name != "Referer" {
+				req.Header.Del(name)
+			}
+		}
+	}
+	info, ok := req.Context().Value(fetchInfoKey{}).(*fetchInfo)
+	if !ok {
+		return nil
+	}
+	code := req.Response.StatusCode
+	permanent := code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect
+	info.Permanent = permanent && (len(via) == 1 || info.Permanent)
+	return nil
+}
+
+func (f *fetcher) fetchFeed(ctx context.Context, feedurl string, creds feedCredentials) (*RSSFeed, fetchInfo, error) {
+	feed := new(RSSFeed)
+	info := fetchInfo{FinalURL: feedurl}
+	res, err := f.get(ctx, feedurl, creds, &info)
+	if err != nil {
+		return feed, info, err
+	}
+	defer res.Body.Close()
+	if err := checkContentType(res.Header.Get("Content-Type")); err != nil {
+		return feed, info, &parseError{err}
+	}
+	data, err := f.readBody(res, &info)
+	if err != nil {
+		return feed, info, err
+	}
+	if err := checkXML(data); err != nil {
+		return feed, info, &parseError{err}
+	}
+	if err := xml.Unmarshal(data, feed); err != nil {
+		return feed, info, &parseError{fmt.Errorf("error: Unmarshal -> %w", err)}
+	}
+	feed.unescapeHTML()
+	return feed, info, nil
+}
+
+// fetchPage fetches a web page, such as the article a post links to, and
+// returns it decoded to utf-8.
+func (f *fetcher) fetchPage(ctx context.Context, pageurl string) ([]byte, fetchInfo, error) {
+	info := fetchInfo{FinalURL: pageurl}
+	res, err := f.get(ctx, pageurl, feedCredentials{}, &info)
+	if err != nil {
+		return nil, info, err
+	}
+	defer res.Body.Close()
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, info, fmt.Errorf("error: Content-Type %v is not a web page", mediaType)
+	}
+	data, err := f.readBody(res, &info)
+	if err != nil {
+		return nil, info, err
+	}
+	utf8, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return nil, info, fmt.Errorf("error: unreadable page encoding -> %w", err)
+	}
+	data, err = io.ReadAll(utf8)
+	if err != nil {
+		return nil, info, fmt.Errorf("error: unreadable page encoding -> %w", err)
+	}
+	return data, info, nil
+}
+
+// get sends a GET for target with the fetcher's headers and creds, recording
+// where it ended up in info. Responses outside 2xx are returned as a
+// statusError with the body already closed.
+func (f *fetcher) get(ctx context.Context, target string, creds feedCredentials, info *fetchInfo) (*http.Response, error) {
+	ctx = context.WithValue(ctx, fetchInfoKey{}, info)
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error: request -> %w", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if err := creds.apply(req); err != nil {
+		return nil, err
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error: response -> %w", err)
+	}
+	info.FinalURL = res.Request.URL.String()
+	info.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, &statusError{
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return res, nil
+}
+
+// readBody decompresses the response, reading at most maxBodyBytes both off
+// the wire and out of the decoder so neither a hostile url nor a compression
+// bomb can exhaust memory.
+func (f *fetcher) readBody(res *http.Response, info *fetchInfo) ([]byte, error) {
+	tooLarge := fmt.Errorf("error: response is larger than the %d byte limit, raise fetch max_body_bytes if the feed is genuine", f.maxBodyBytes)
+	if res.ContentLength > f.maxBodyBytes {
+		return nil, tooLarge
+	}
+	wire := &countingReader{r: io.LimitReader(res.Body, f.maxBodyBytes+1)}
+	body, err := decodeBody(wire, res.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, f.maxBodyBytes+1))
+	info.WireBytes = wire.n
+	info.BodyBytes = int64(len(data))
+	if wire.n > f.maxBodyBytes || info.BodyBytes > f.maxBodyBytes {
+		return nil, tooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error: Reading response -> %w", err)
+	}
+	return data, nil
+}
+
+func decodeBody(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("error: reading gzip response -> %w", err)
+		}
+		return gz, nil
+	case "deflate":
+		// Servers disagree on whether deflate means zlib wrapped or raw, so peek.
+		br := bufio.NewReader(r)
+		header, err := br.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("error: reading deflate response -> %w", err)
+			}
+			return zr, nil
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return brotli.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("error: unsupported Content-Encoding %v", encoding)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// checkContentType rejects responses that are clearly not a feed, like an html
+// error page. Servers often send feeds as text/plain or with no type at all,
+// so those are let through to the xml checks.
+func checkContentType(header string) error {
+	if header == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return fmt.Errorf("error: unreadable Content-Type %q -> %w", header, err)
+	}
+	if strings.HasSuffix(mediaType, "xml") || mediaType == "text/plain" || mediaType == "application/octet-stream" {
+		return nil
+	}
+	return fmt.Errorf("error: Content-Type %v does not look like a feed", mediaType)
+}
+
+// parseRetryAfter reads a Retry-After header given either as seconds or as an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	when, err := http.ParseTime(header)
+	if err != nil || when.Before(now) {
+		return 0
+	}
+	return when.Sub(now)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"seconds with spaces", " 30 ", 30 * time.Second},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"http date in the past", now.Add(-time.Hour).Format(http.TimeFormat), 0},
+		{"garbage", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.header, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		header  string
+		wantErr bool
+	}{
+		{"", false},
+		{"application/rss+xml", false},
+		{"application/atom+xml; charset=utf-8", false},
+		{"text/xml", false},
+		{"text/plain", false},
+		{"application/octet-stream", false},
+		{"text/html; charset=utf-8", true},
+		{"application/json", true},
+		{"not a type;;", true},
+	}
+	for _, tt := range tests {
+		err := checkContentType(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkContentType(%q) error = %v, want error %v", tt.header, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	const limit = 64
+	f := &fetcher{maxBodyBytes: limit}
+	tests := []struct {
+		name          string
+		size          int
+		contentLength int64
+		wantErr       bool
+	}{
+		{"under the limit", limit - 1, -1, false},
+		{"at the limit", limit, -1, false},
+		{"over the limit", limit + 1, -1, true},
+		{"declared over the limit", 1, limit + 1, true},
+		{"declared short but sends more", limit * 4, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{
+				Header:        http.Header{},
+				ContentLength: tt.contentLength,
+				Body:          io.NopCloser(bytes.NewReader(bytes.Repeat([]byte("a"), tt.size))),
+			}
+			var info fetchInfo
+			data, err := f.readBody(res, &info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readBody() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && len(data) != tt.size {
+				t.Errorf("readBody() read %d bytes, want %d", len(data), tt.size)
+			}
+		})
+	}
+}
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		return data
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	feed := []byte(`<rss><channel><title>gator</title></channel></rss>`)
+	tests := []struct {
+		name     string
+		header   string
+		body     []byte
+		wantErr  bool
+		wantData bool
+	}{
+		{"identity", "", feed, false, true},
+		{"explicit identity", "identity", feed, false, true},
+		{"gzip", "gzip", compress(t, "gzip", feed), false, true},
+		{"x-gzip upper case", " X-GZIP ", compress(t, "gzip", feed), false, true},
+		{"zlib wrapped deflate", "deflate", compress(t, "zlib", feed), false, true},
+		{"raw deflate", "deflate", compress(t, "flate", feed), false, true},
+		{"brotli", "br", compress(t, "br", feed), false, true},
+		{"gzip header missing", "gzip", feed, true, false},
+		{"unknown encoding", "compress", feed, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := decodeBody(bytes.NewReader(tt.body), tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeBody() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantData {
+				return
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, feed) {
+				t.Errorf("decodeBody() = %q, want %q", got, feed)
+			}
+		})
+	}
+}
+
+func TestReadBodyCompressionBomb(t *testing.T) {
+	const limit = 4 << 10
+	f := &fetcher{maxBodyBytes: limit}
+	bomb := compress(t, "gzip", bytes.Repeat([]byte("a"), 1<<20))
+	if len(bomb) > limit {
+		t.Fatalf("test bomb is %d bytes compressed, want it under the %d byte limit", len(bomb), limit)
+	}
+	res := &http.Response{
+		Header:        http.Header{"Content-Encoding": {"gzip"}},
+		ContentLength: int64(len(bomb)),
+		Body:          io.NopCloser(bytes.NewReader(bomb)),
+	}
+	var info fetchInfo
+	if _, err := f.readBody(res, &info); err == nil {
+		t.Fatal("readBody() decoded past the size limit")
+	}
+	if info.WireBytes != int64(len(bomb)) || info.BodyBytes != limit+1 {
+		t.Errorf("readBody() counted %d wire and %d body bytes, want %d and %d", info.WireBytes, info.BodyBytes, len(bomb), limit+1)
+	}
+}
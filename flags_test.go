@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantPos   []string
+		wantQuiet bool
+		wantErr   bool
+	}{
+		{"flags first", []string{"--quiet", "30s"}, []string{"30s"}, true, false},
+		{"flags after positionals", []string{"30s", "--quiet"}, []string{"30s"}, true, false},
+		{"no flags", []string{"30s", "extra"}, []string{"30s", "extra"}, false, false},
+		{"unknown flag", []string{"30s", "--loud"}, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFlagSet("agg")
+			quiet := fs.Bool("quiet", false, "")
+			got, err := parseFlags(fs, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFlags() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(got, tt.wantPos) || *quiet != tt.wantQuiet {
+				t.Errorf("parseFlags() = %q, quiet %v, want %q, quiet %v", got, *quiet, tt.wantPos, tt.wantQuiet)
+			}
+		})
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		value   string
+		want    time.Time
+		approx  bool // relative to now, allow for the time the test takes
+		wantErr bool
+	}{
+		{"7d", now.AddDate(0, 0, -7), true, false},
+		{"0d", now, true, false},
+		{"24h", now.Add(-24 * time.Hour), true, false},
+		{"90m", now.Add(-90 * time.Minute), true, false},
+		{"2026-10-01", time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local), false, false},
+		{"2026-10-01 08:30:00", time.Date(2026, 10, 1, 8, 30, 0, 0, time.Local), false, false},
+		{"2026-10-01T08:30:00Z", time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC), false, false},
+		{"yesterday", time.Time{}, false, true},
+		{"7days", time.Time{}, false, true},
+		{"", time.Time{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimeFlag(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimeFlag(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.approx {
+				if diff := got.Sub(tt.want); diff < 0 || diff > time.Minute {
+					t.Errorf("parseTimeFlag(%q) = %v, want about %v", tt.value, got, tt.want)
+				}
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseTimeFlag(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"24h", "24h"},
+		{"2026-10-01", "2026-10-01"},
+		{"https://example.com/feed.xml", "https://example.com/feed.xml"},
+		{"", "''"},
+		{"100%", "'100%'"},
+		{"hacker news", "'hacker news'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{`a"b`, `'a"b'`},
+		{"a;rm -rf /", "'a;rm -rf /'"},
+	}
+	for _, tt := range tests {
+		got := shellQuote(tt.value)
+		if got != tt.want {
+			t.Errorf("shellQuote(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+		args, err := splitArgs("browse --match=" + got)
+		if err != nil || len(args) != 2 || args[1] != "--match="+tt.value {
+			t.Errorf("shellQuote(%q) = %v does not split back to the value, got %q", tt.value, got, args)
+		}
+	}
+}
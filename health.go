@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+const (
+	defaultHealthDays = 30
+	// staleAfter flags feeds that haven't produced a new post in this long.
+	staleAfter = 90 * 24 * time.Hour
+)
+
+func handlerHealth(s *state, cmd command) error {
+	days := defaultHealthDays
+	if len(cmd.args) > 0 {
+		d, err := strconv.Atoi(cmd.args[0])
+		if err != nil || d < 1 {
+			return fmt.Errorf("error: health takes an optional number of days to report on, use 'health 7' for the last week")
+		}
+		days = d
+	}
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not fetch feeds -> %w", err)
+	}
+	stats, err := s.db.GetFetchStats(context.Background(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return fmt.Errorf("error: could not fetch fetch history -> %w", err)
+	}
+	lastPosts, err := s.db.GetLastPostTimes(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not fetch latest post times -> %w", err)
+	}
+	statsByFeed := make(map[uuid.UUID]database.GetFetchStatsRow, len(stats))
+	for _, st := range stats {
+		statsByFeed[st.FeedID] = st
+	}
+	lastPostByFeed := make(map[uuid.UUID]time.Time, len(lastPosts))
+	for _, lp := range lastPosts {
+		lastPostByFeed[lp.FeedID] = lp.LastPostAt
+	}
+	if s.output != outputTable {
+		records := make([]healthRecord, 0, len(feeds))
+		for i := range feeds {
+			record := healthRecord{
+				FeedID: feeds[i].ID,
+				Name:   feeds[i].Name,
+				URL:    feeds[i].Url,
+				DeadAt: nullTime(feeds[i].DeadAt),
+			}
+			if st, ok := statsByFeed[feeds[i].ID]; ok {
+				record.Attempts = st.Attempts
+				record.Successes = st.Successes
+				record.AvgLatencyMs = st.AvgDurationMs
+				record.LastAttemptAt = &st.LastAttemptAt
+			}
+			lastPost := feeds[i].CreatedAt
+			if lp, ok := lastPostByFeed[feeds[i].ID]; ok {
+				record.LastPostAt = &lp
+				lastPost = lp
+			}
+			record.Stale = !feeds[i].DeadAt.Valid && time.Since(lastPost) > staleAfter
+			records = append(records, record)
+		}
+		return writeRecords(s.output, records)
+	}
+	fmt.Printf(`
+░█▀▀░█▀▀░█▀▀░█▀▄░░░█░█░█▀▀░█▀█░█░░░▀█▀░█░█
+░█▀▀░█▀▀░█▀▀░█░█░░░█▀█░█▀▀░█▀█░█░░░░█░░█▀█
+░▀░░░▀▀▀░▀▀▀░▀▀░░░░▀░▀░▀▀▀░▀░▀░▀▀▀░░▀░░▀░▀` + "\n")
+	fmt.Printf("»»»» fetches over the last %d days\n", days)
+	for i := range feeds {
+		fmt.Printf("\n»»»» %v\n", feeds[i].Name)
+		fmt.Printf("Url » %v\n", feeds[i].Url)
+		if st, ok := statsByFeed[feeds[i].ID]; ok {
+			fmt.Printf("Success » %d/%d (%d%%)\n", st.Successes, st.Attempts, st.Successes*100/st.Attempts)
+			fmt.Printf("Avg latency » %v\n", time.Duration(st.AvgDurationMs*float64(time.Millisecond)).Round(time.Millisecond))
+			fmt.Printf("Last attempt » %v\n", st.LastAttemptAt.Format(time.DateTime))
+		} else {
+			fmt.Printf("Success » no fetches in the last %d days\n", days)
+		}
+		lastPost, ok := lastPostByFeed[feeds[i].ID]
+		if ok {
+			fmt.Printf("Last new post » %v\n", lastPost.Format(time.DateTime))
+		} else {
+			fmt.Printf("Last new post » never\n")
+			lastPost = feeds[i].CreatedAt
+		}
+		if feeds[i].DeadAt.Valid {
+			fmt.Printf("Status » dead since %v\n", feeds[i].DeadAt.Time.Format(time.DateTime))
+		} else if time.Since(lastPost) > staleAfter {
+			fmt.Printf("Status » stale, nothing new in %d days\n", int(staleAfter.Hours()/24))
+		}
+	}
+	fmt.Printf("\n")
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdfincher/gator/internal/config"
+)
+
+const (
+	defaultHostMaxConns = 2
+	defaultHostMinDelay = time.Second
+)
+
+// hostLimiter keeps gator polite towards servers hosting many feeds: no more
+// than maxConns requests in flight per host, started at least minDelay apart.
+type hostLimiter struct {
+	mu       sync.Mutex
+	maxConns int
+	minDelay time.Duration
+	hosts    map[string]*hostSlot
+}
+
+type hostSlot struct {
+	conns chan struct{}
+	next  time.Time // earliest time the next request to the host may start
+}
+
+func newHostLimiter(cfg *config.Config) *hostLimiter {
+	l := &hostLimiter{
+		maxConns: defaultHostMaxConns,
+		minDelay: defaultHostMinDelay,
+		hosts:    make(map[string]*hostSlot),
+	}
+	if cfg.Fetch.HostMaxConns > 0 {
+		l.maxConns = cfg.Fetch.HostMaxConns
+	}
+	if cfg.Fetch.HostMinDelay.Duration > 0 {
+		l.minDelay = cfg.Fetch.HostMinDelay.Duration
+	}
+	return l
+}
+
+// acquire blocks until a request to host may start. The returned release func
+// must be called once the request is finished with.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{conns: make(chan struct{}, l.maxConns)}
+		l.hosts[host] = slot
+	}
+	l.mu.Unlock()
+
+	select {
+	case slot.conns <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slot.conns }
+
+	l.mu.Lock()
+	start := time.Now()
+	if slot.next.After(start) {
+		start = slot.next
+	}
+	slot.next = start.Add(l.minDelay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// politeTransport applies a hostLimiter to every request it sends, redirects
+// included, holding the host's slot until the response body is closed.
+type politeTransport struct {
+	next    http.RoundTripper
+	limiter *hostLimiter
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &releaseOnClose{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
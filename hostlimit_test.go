@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLimiter(maxConns int, minDelay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		maxConns: maxConns,
+		minDelay: minDelay,
+		hosts:    make(map[string]*hostSlot),
+	}
+}
+
+func TestHostLimiterLimits(t *testing.T) {
+	const minDelay = 40 * time.Millisecond
+	// timers never fire early, but the goroutine recording a start can be
+	// late, so allow a little slack between recorded starts
+	const slack = 10 * time.Millisecond
+	tests := []struct {
+		name     string
+		maxConns int
+		hold     time.Duration
+		requests int
+	}{
+		{"one at a time", 1, 60 * time.Millisecond, 4},
+		{"two at a time", 2, 120 * time.Millisecond, 6},
+		{"released quickly", 3, 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testLimiter(tt.maxConns, minDelay)
+			var inFlight, most atomic.Int32
+			var mu sync.Mutex
+			var starts []time.Time
+			var wg sync.WaitGroup
+			for i := range tt.requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					// vary the case, hosts are matched case insensitively
+					host := "example.com"
+					if i%2 == 1 {
+						host = "EXAMPLE.com"
+					}
+					release, err := l.acquire(context.Background(), host)
+					if err != nil {
+						t.Errorf("acquire() error = %v", err)
+						return
+					}
+					n := inFlight.Add(1)
+					for {
+						m := most.Load()
+						if n <= m || most.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					mu.Lock()
+					starts = append(starts, time.Now())
+					mu.Unlock()
+					time.Sleep(tt.hold)
+					inFlight.Add(-1)
+					release()
+				}()
+			}
+			wg.Wait()
+			if got := int(most.Load()); got > tt.maxConns {
+				t.Errorf("%d requests in flight at once, want at most %d", got, tt.maxConns)
+			}
+			if len(starts) != tt.requests {
+				t.Fatalf("%d requests started, want %d", len(starts), tt.requests)
+			}
+			slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+			for i := 1; i < len(starts); i++ {
+				if gap := starts[i].Sub(starts[i-1]); gap < minDelay-slack {
+					t.Errorf("requests %d and %d started %v apart, want at least %v", i-1, i, gap, minDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestHostLimiterSeparateHosts(t *testing.T) {
+	l := testLimiter(1, time.Hour)
+	first, err := l.acquire(context.Background(), "a.example")
+	if err != nil {
+		t.Fatalf("acquire(a.example) error = %v", err)
+	}
+	defer first()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	second, err := l.acquire(ctx, "b.example")
+	if err != nil {
+		t.Fatalf("acquire(b.example) error = %v, want it not held up by a.example", err)
+	}
+	second()
+}
+
+func TestHostLimiterCancelReleasesSlot(t *testing.T) {
+	tests := []struct {
+		name     string
+		minDelay time.Duration
+		hold     bool // keep the first request's slot while the second waits
+	}{
+		{"cancelled waiting for a connection", 0, true},
+		{"cancelled waiting out the delay", time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testLimiter(1, tt.minDelay)
+			first, err := l.acquire(context.Background(), "example.com")
+			if err != nil {
+				t.Fatalf("first acquire() error = %v", err)
+			}
+			if !tt.hold {
+				first()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := l.acquire(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("acquire() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if tt.hold {
+				first()
+			}
+			if n := len(l.hosts["example.com"].conns); n != 0 {
+				t.Errorf("%d connection slots still taken after cancel and release, want 0", n)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestPoliteTransportReleases(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"released when the body is closed", nil, false},
+		{"released when the request fails", errors.New("connection refused"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testLimiter(1, 0)
+			transport := &politeTransport{
+				limiter: l,
+				next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+				}),
+			}
+			req, err := http.NewRequest(http.MethodGet, "http://example.com/feed", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := transport.RoundTrip(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RoundTrip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			slot := l.hosts["example.com"]
+			if err == nil {
+				if n := len(slot.conns); n != 1 {
+					t.Fatalf("%d connection slots taken while the body is open, want 1", n)
+				}
+				res.Body.Close()
+				res.Body.Close()
+			}
+			if n := len(slot.conns); n != 0 {
+				t.Errorf("%d connection slots still taken, want 0", n)
+			}
+		})
+	}
+}
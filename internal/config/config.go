@@ -5,11 +5,51 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 )
 
 type Config struct {
-	DBURL    string `json:"db_url"`
-	UserName string `json:"current_user_name"`
+	DBURL    string      `json:"db_url"`
+	UserName string      `json:"current_user_name"`
+	Fetch    FetchConfig `json:"fetch,omitzero"`
+}
+
+// FetchConfig tunes how feeds are fetched by agg, zero values use gator's defaults.
+type FetchConfig struct {
+	Timeout            Duration `json:"timeout,omitzero"`
+	UserAgent          string   `json:"user_agent,omitzero"`
+	Proxy              string   `json:"proxy,omitzero"`
+	CAFile             string   `json:"ca_file,omitzero"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify,omitzero"`
+	MaxBackoff         Duration `json:"max_backoff,omitzero"`
+	HostMaxConns       int      `json:"host_max_conns,omitzero"`
+	HostMinDelay       Duration `json:"host_min_delay,omitzero"`
+	MaxBodyBytes       int64    `json:"max_body_bytes,omitzero"`
+
+	BlockPrivateAddresses bool     `json:"block_private_addresses,omitzero"`
+	AllowHosts            []string `json:"allow_hosts,omitzero"`
+}
+
+// Duration is a time.Duration stored in the config as a string such as "90s" or "6h".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("error duration must be a string like \"30s\" -> %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("error parsing duration -> %w", err)
+	}
+	d.Duration = parsed
+	return nil
 }
 
 func Read() (*Config, error) {
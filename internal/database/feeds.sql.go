@@ -13,6 +13,18 @@ import (
 	"github.com/google/uuid"
 )
 
+const countDueFeeds = `-- name: CountDueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE dead_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp)
+`
+
+func (q *Queries) CountDueFeeds(ctx context.Context, now time.Time) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDueFeeds, now)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds(id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -23,7 +35,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, final_url, redirect_url, redirect_count, dead_at, next_fetch_at, last_status, last_error, consecutive_failures, auth_type, auth_username, auth_secret, bytes_transferred, bytes_decoded
 `
 
 type CreateFeedParams struct {
@@ -53,12 +65,59 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FinalUrl,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeadAt,
+		&i.NextFetchAt,
+		&i.LastStatus,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.AuthType,
+		&i.AuthUsername,
+		&i.AuthSecret,
+		&i.BytesTransferred,
+		&i.BytesDecoded,
 	)
 	return i, err
 }
 
+const createFeedURLHistory = `-- name: CreateFeedURLHistory :exec
+INSERT INTO feed_url_history (id, created_at, feed_id, url)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (feed_id, url) DO NOTHING
+`
+
+type CreateFeedURLHistoryParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Url       string
+}
+
+func (q *Queries) CreateFeedURLHistory(ctx context.Context, arg CreateFeedURLHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedURLHistory,
+		arg.ID,
+		arg.CreatedAt,
+		arg.FeedID,
+		arg.Url,
+	)
+	return err
+}
+
 const getFeedID = `-- name: GetFeedID :one
-SELECT id FROM feeds WHERE url = $1
+SELECT id FROM (
+  SELECT feeds.id, 0 AS rank, feeds.created_at FROM feeds WHERE feeds.url = $1
+  UNION ALL
+  SELECT feed_url_history.feed_id, 1 AS rank, feed_url_history.created_at FROM feed_url_history WHERE feed_url_history.url = $1
+) AS matches
+ORDER BY rank, created_at DESC
+LIMIT 1
 `
 
 func (q *Queries) GetFeedID(ctx context.Context, url string) (uuid.UUID, error) {
@@ -68,8 +127,24 @@ func (q *Queries) GetFeedID(ctx context.Context, url string) (uuid.UUID, error)
 	return id, err
 }
 
+const getFeedIDForUser = `-- name: GetFeedIDForUser :one
+SELECT id FROM feeds WHERE url = $1 AND user_id = $2
+`
+
+type GetFeedIDForUserParams struct {
+	Url    string
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetFeedIDForUser(ctx context.Context, arg GetFeedIDForUserParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getFeedIDForUser, arg.Url, arg.UserID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, final_url, redirect_url, redirect_count, dead_at, next_fetch_at, last_status, last_error, consecutive_failures, auth_type, auth_username, auth_secret, bytes_transferred, bytes_decoded FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -89,6 +164,19 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.FinalUrl,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.DeadAt,
+			&i.NextFetchAt,
+			&i.LastStatus,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.AuthType,
+			&i.AuthUsername,
+			&i.AuthSecret,
+			&i.BytesTransferred,
+			&i.BytesDecoded,
 		); err != nil {
 			return nil, err
 		}
@@ -104,44 +192,192 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, url, updated_at, last_fetched_at
+SELECT id, url, updated_at, last_fetched_at, redirect_url, redirect_count, consecutive_failures,
+  auth_type, auth_username, auth_secret
 FROM feeds
+WHERE dead_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp)
 ORDER BY last_fetched_at NULLS FIRST, updated_at ASC
 LIMIT 1
 `
 
 type GetNextFeedToFetchRow struct {
-	ID            uuid.UUID
-	Url           string
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	Url                 string
+	UpdatedAt           time.Time
+	LastFetchedAt       sql.NullTime
+	RedirectUrl         sql.NullString
+	RedirectCount       int32
+	ConsecutiveFailures int32
+	AuthType            sql.NullString
+	AuthUsername        sql.NullString
+	AuthSecret          sql.NullString
 }
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetNextFeedToFetch(ctx context.Context, now time.Time) (GetNextFeedToFetchRow, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch, now)
 	var i GetNextFeedToFetchRow
 	err := row.Scan(
 		&i.ID,
 		&i.Url,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.ConsecutiveFailures,
+		&i.AuthType,
+		&i.AuthUsername,
+		&i.AuthSecret,
 	)
 	return i, err
 }
 
+const markFeedDead = `-- name: MarkFeedDead :exec
+UPDATE feeds
+SET updated_at = $1, dead_at = $2, last_status = $3, last_error = $4
+WHERE id = $5
+`
+
+type MarkFeedDeadParams struct {
+	UpdatedAt  time.Time
+	DeadAt     sql.NullTime
+	LastStatus sql.NullInt32
+	LastError  sql.NullString
+	ID         uuid.UUID
+}
+
+func (q *Queries) MarkFeedDead(ctx context.Context, arg MarkFeedDeadParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedDead,
+		arg.UpdatedAt,
+		arg.DeadAt,
+		arg.LastStatus,
+		arg.LastError,
+		arg.ID,
+	)
+	return err
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds 
-SET updated_at = $1, last_fetched_at = $2
-WHERE id = $3
+SET updated_at = $1, last_fetched_at = $2, final_url = $3, redirect_url = $4, redirect_count = $5,
+    last_status = $6, last_error = NULL, next_fetch_at = NULL, consecutive_failures = 0,
+    bytes_transferred = bytes_transferred + $7, bytes_decoded = bytes_decoded + $8
+WHERE id = $9
 `
 
 type MarkFeedFetchedParams struct {
+	UpdatedAt        time.Time
+	LastFetchedAt    sql.NullTime
+	FinalUrl         sql.NullString
+	RedirectUrl      sql.NullString
+	RedirectCount    int32
+	LastStatus       sql.NullInt32
+	BytesTransferred int64
+	BytesDecoded     int64
+	ID               uuid.UUID
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		arg.UpdatedAt,
+		arg.LastFetchedAt,
+		arg.FinalUrl,
+		arg.RedirectUrl,
+		arg.RedirectCount,
+		arg.LastStatus,
+		arg.BytesTransferred,
+		arg.BytesDecoded,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedError = `-- name: RecordFeedError :exec
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $2, last_status = $3, last_error = $4, next_fetch_at = $5,
+    consecutive_failures = consecutive_failures + 1
+WHERE id = $6
+`
+
+type RecordFeedErrorParams struct {
 	UpdatedAt     time.Time
 	LastFetchedAt sql.NullTime
+	LastStatus    sql.NullInt32
+	LastError     sql.NullString
+	NextFetchAt   sql.NullTime
 	ID            uuid.UUID
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.UpdatedAt, arg.LastFetchedAt, arg.ID)
+func (q *Queries) RecordFeedError(ctx context.Context, arg RecordFeedErrorParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedError,
+		arg.UpdatedAt,
+		arg.LastFetchedAt,
+		arg.LastStatus,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.ID,
+	)
+	return err
+}
+
+const resetFeedRedirect = `-- name: ResetFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = $1
+WHERE id = $2
+`
+
+type ResetFeedRedirectParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) ResetFeedRedirect(ctx context.Context, arg ResetFeedRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, resetFeedRedirect, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedAuth = `-- name: SetFeedAuth :execrows
+UPDATE feeds
+SET auth_type = $1, auth_username = $2, auth_secret = $3, updated_at = $4
+WHERE url = $5 AND user_id = $6
+`
+
+type SetFeedAuthParams struct {
+	AuthType     sql.NullString
+	AuthUsername sql.NullString
+	AuthSecret   sql.NullString
+	UpdatedAt    time.Time
+	Url          string
+	UserID       uuid.UUID
+}
+
+func (q *Queries) SetFeedAuth(ctx context.Context, arg SetFeedAuthParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedAuth,
+		arg.AuthType,
+		arg.AuthUsername,
+		arg.AuthSecret,
+		arg.UpdatedAt,
+		arg.Url,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2, final_url = NULL, redirect_url = NULL, redirect_count = 0
+WHERE id = $3
+`
+
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
 	return err
 }
@@ -87,7 +87,13 @@ func (q *Queries) DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFol
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_id, feeds.url, feeds.name AS feed_name FROM feed_follows
+SELECT feed_id, feeds.url, feeds.name AS feed_name,
+  (SELECT COUNT(*) FROM posts
+   WHERE posts.feed_id = feed_follows.feed_id
+     AND NOT EXISTS (
+       SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+     )) AS unread
+FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
 `
@@ -96,6 +102,7 @@ type GetFeedFollowsForUserRow struct {
 	FeedID   uuid.UUID
 	Url      string
 	FeedName string
+	Unread   int64
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -107,7 +114,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedID, &i.Url, &i.FeedName); err != nil {
+		if err := rows.Scan(&i.FeedID, &i.Url, &i.FeedName, &i.Unread); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -12,13 +12,26 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	FinalUrl            sql.NullString
+	RedirectUrl         sql.NullString
+	RedirectCount       int32
+	DeadAt              sql.NullTime
+	NextFetchAt         sql.NullTime
+	LastStatus          sql.NullInt32
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	AuthType            sql.NullString
+	AuthUsername        sql.NullString
+	AuthSecret          sql.NullString
+	BytesTransferred    int64
+	BytesDecoded        int64
 }
 
 type FeedFollow struct {
@@ -29,15 +42,64 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FeedHeader struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Name      string
+	Value     string
+}
+
+type FeedUrlHistory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Url       string
+}
+
+type FetchLog struct {
+	ID         uuid.UUID
+	FeedID     uuid.UUID
+	StartedAt  time.Time
+	DurationMs int32
+	HttpStatus sql.NullInt32
+	Bytes      int64
+	ItemsSeen  int32
+	NewPosts   int32
+	Error      sql.NullString
+}
+
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Title            string
+	Url              string
+	Description      string
+	PublishedAt      sql.NullTime
+	FeedID           uuid.UUID
+	Content          sql.NullString
+	ContentFetchedAt sql.NullTime
+	Search           interface{}
+}
+
+type PostHandle struct {
+	UserID uuid.UUID
+	Handle int32
+	PostID uuid.UUID
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
 }
 
 type User struct {
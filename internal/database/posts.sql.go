@@ -11,81 +11,215 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
+const createPosts = `-- name: CreatePosts :many
 INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-) RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+SELECT item.id, $1::timestamp, $1::timestamp, item.title, item.url, item.description, item.published_at, $2::uuid
+FROM unnest(
+    $3::uuid[],
+    $4::text[],
+    $5::text[],
+    $6::text[],
+    $7::timestamp[]
+) AS item(id, title, url, description, published_at)
+ON CONFLICT (url) DO NOTHING
+RETURNING url
 `
 
-type CreatePostParams struct {
+type CreatePostsParams struct {
+	Now          time.Time
+	FeedID       uuid.UUID
+	Ids          []uuid.UUID
+	Titles       []string
+	Urls         []string
+	Descriptions []string
+	PublishedAt  []time.Time
+}
+
+func (q *Queries) CreatePosts(ctx context.Context, arg CreatePostsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, createPosts,
+		arg.Now,
+		arg.FeedID,
+		pq.Array(arg.Ids),
+		pq.Array(arg.Titles),
+		pq.Array(arg.Urls),
+		pq.Array(arg.Descriptions),
+		pq.Array(arg.PublishedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findPosts = `-- name: FindPosts :many
+SELECT id, title, url, description, published_at, content FROM posts
+WHERE (url = $1 OR id::text LIKE $2::text || '%')
+  AND (feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $3)
+    OR id IN (SELECT post_id FROM post_stars WHERE post_stars.user_id = $3))
+ORDER BY id
+LIMIT 2
+`
+
+type FindPostsParams struct {
+	Ref      string
+	IdPrefix sql.NullString
+	UserID   uuid.UUID
+}
+
+type FindPostsRow struct {
 	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
 	Title       string
 	Url         string
 	Description string
 	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	Content     sql.NullString
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+func (q *Queries) FindPosts(ctx context.Context, arg FindPostsParams) ([]FindPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, findPosts, arg.Ref, arg.IdPrefix, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindPostsRow
+	for rows.Next() {
+		var i FindPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastPostTimes = `-- name: GetLastPostTimes :many
+SELECT feed_id, MAX(created_at)::timestamp AS last_post_at
+FROM posts
+GROUP BY feed_id
+`
+
+type GetLastPostTimesRow struct {
+	FeedID     uuid.UUID
+	LastPostAt time.Time
+}
+
+func (q *Queries) GetLastPostTimes(ctx context.Context) ([]GetLastPostTimesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLastPostTimes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLastPostTimesRow
+	for rows.Next() {
+		var i GetLastPostTimesRow
+		if err := rows.Scan(&i.FeedID, &i.LastPostAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, post_reads.read_at, COALESCE(posts.published_at, posts.updated_at)::timestamp AS sort_at
 FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY COALESCE(posts.published_at, posts.updated_at) DESC
-LIMIT $2
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND ($2::boolean OR post_reads.read_at IS NULL)
+  AND ($3::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.updated_at), posts.id) < ($3, $4::uuid))
+  AND ($5::uuid IS NULL OR posts.feed_id = $5)
+  AND ($6::timestamp IS NULL OR COALESCE(posts.published_at, posts.updated_at) >= $6)
+  AND ($7::timestamp IS NULL OR COALESCE(posts.published_at, posts.updated_at) < $7)
+  AND ($8::text IS NULL
+    OR posts.title ILIKE '%' || $8 || '%' ESCAPE '\' OR posts.description ILIKE '%' || $8 || '%' ESCAPE '\')
+ORDER BY COALESCE(posts.published_at, posts.updated_at) DESC, posts.id DESC
+LIMIT $9 OFFSET $10
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID      uuid.UUID
+	IncludeRead bool
+	AfterAt     sql.NullTime
+	AfterID     uuid.NullUUID
+	FeedID      uuid.NullUUID
+	Since       sql.NullTime
+	Until       sql.NullTime
+	Match       sql.NullString
+	MaxPosts    int32
+	SkipPosts   int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	ReadAt      sql.NullTime
+	SortAt      time.Time
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.IncludeRead,
+		arg.AfterAt,
+		arg.AfterID,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.Match,
+		arg.MaxPosts,
+		arg.SkipPosts,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -95,6 +229,8 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.ReadAt,
+			&i.SortAt,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +244,91 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name,
+  ts_rank(posts.search, search.query)::real AS rank,
+  ts_headline('english', posts.title || ' ' || posts.description || ' ' || COALESCE(posts.content, ''), search.query,
+    'StartSel=**, StopSel=**, MaxFragments=2, MinWords=8, MaxWords=20, FragmentDelimiter=" … "')::text AS snippet
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+CROSS JOIN (
+  SELECT websearch_to_tsquery('english', $1::text) && to_tsquery('english', $2::text) AS query
+) AS search
+WHERE posts.search @@ search.query
+  AND ($3::boolean OR EXISTS (
+    SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $4
+  ))
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.updated_at) DESC
+LIMIT $5
+`
+
+type SearchPostsParams struct {
+	Terms    string
+	Prefixes string
+	AllPosts bool
+	UserID   uuid.UUID
+	MaxPosts int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Terms,
+		arg.Prefixes,
+		arg.AllPosts,
+		arg.UserID,
+		arg.MaxPosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts SET content = $1, content_fetched_at = $2 WHERE id = $3
+`
+
+type SetPostContentParams struct {
+	Content          sql.NullString
+	ContentFetchedAt sql.NullTime
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.Content, arg.ContentFetchedAt, arg.ID)
+	return err
+}
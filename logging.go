@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// newAggLogger builds the structured logger agg writes to instead of banners,
+// so its output reads well under systemd/journald and can be grepped.
+func newAggLogger(format string, quiet, verbose bool) (*slog.Logger, error) {
+	if quiet && verbose {
+		return nil, fmt.Errorf("error: --quiet and --verbose can't be used together")
+	}
+	level := slog.LevelInfo
+	if quiet {
+		level = slog.LevelError
+	}
+	if verbose {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("error: unknown log format '%v', use text or json", format)
+}
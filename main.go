@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
@@ -16,8 +18,14 @@ import (
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	sqlDB   *sql.DB
+	db      *database.Queries
+	cfg     *config.Config
+	fetcher *fetcher // built on first use by ensureFetcher
+	log     *slog.Logger
+	metrics *aggMetrics
+	session bool   // running inside gator shell
+	output  string // format for listing commands, see output.go
 }
 
 type command struct {
@@ -50,7 +58,9 @@ func handlerLogin(s *state, cmd command) error {
 	if err != nil {
 		return fmt.Errorf("error: user %v does not exist, use <register %v> to create user -> %w", cmd.args[0], cmd.args[0], err)
 	}
-	if err := s.cfg.SetUser(cmd.args[0]); err != nil {
+	if s.session {
+		s.cfg.UserName = cmd.args[0]
+	} else if err := s.cfg.SetUser(cmd.args[0]); err != nil {
 		return err
 	}
 	fmt.Printf(`
@@ -113,6 +123,19 @@ func handlerUsers(s *state, cmd command) error {
 	if err != nil {
 		return fmt.Errorf("error: issue retriever user table records -> %w", err)
 	}
+	if s.output != outputTable {
+		records := make([]userRecord, 0, len(users))
+		for i := range users {
+			records = append(records, userRecord{
+				ID:        users[i].ID,
+				Name:      users[i].Name,
+				CreatedAt: users[i].CreatedAt,
+				UpdatedAt: users[i].UpdatedAt,
+				Current:   users[i].Name == s.cfg.UserName,
+			})
+		}
+		return writeRecords(s.output, records)
+	}
 	fmt.Printf(`
 ░█░█░█▀▀░█▀▀░█▀▄░█▀▀
 ░█░█░▀▀█░█▀▀░█▀▄░▀▀█
@@ -129,27 +152,71 @@ func handlerUsers(s *state, cmd command) error {
 }
 
 func handlerAgg(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 {
+	fs := newFlagSet("agg")
+	quiet := fs.Bool("quiet", false, "only log errors")
+	verbose := fs.Bool("verbose", false, "log every post added or skipped")
+	logFormat := fs.String("log-format", "text", "text or json")
+	metricsAddr := fs.String("metrics-addr", "", "address to serve prometheus /metrics on, like :9090")
+	healthAddr := fs.String("health-addr", "", "address to serve /healthz and /readyz on, like :8080")
+	pidfile := fs.String("pidfile", "", "pidfile to lock so only one agg runs on this host")
+	singleton := fs.String("singleton", "", "exit or wait if another agg holds the database lock")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
 		return fmt.Errorf("error: time between requests required use 'agg 5s' to set interval to 5 seconds")
 	}
-	reqInterval, err := time.ParseDuration(cmd.args[0])
+	reqInterval, err := time.ParseDuration(args[0])
 	if err != nil {
 		return fmt.Errorf("error: %w", err)
 	}
+	s.log, err = newAggLogger(*logFormat, *quiet, *verbose)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureFetcher(); err != nil {
+		return err
+	}
+	if *pidfile != "" {
+		release, err := lockPidfile(*pidfile)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	s.metrics = newAggMetrics()
+	if err := startAggServers(s, *metricsAddr, *healthAddr, reqInterval); err != nil {
+		return err
+	}
+	var lock *singletonLock
+	if *singleton != "" {
+		lock, err = acquireSingleton(s, *singleton)
+		if err != nil {
+			return err
+		}
+		defer lock.release()
+	}
 	ticker := time.NewTicker(reqInterval)
 	defer ticker.Stop()
-	fmt.Printf(`
-░█▀▀░█▀█░█░░░█░░░█▀▀░█▀▀░▀█▀░▀█▀░█▀█░█▀▀░░░█▀▀░█▀▀░█▀▀░█▀▄░█▀▀
-░█░░░█░█░█░░░█░░░█▀▀░█░░░░█░░░█░░█░█░█░█░░░█▀▀░█▀▀░█▀▀░█░█░▀▀█
-░▀▀▀░▀▀▀░▀▀▀░▀▀▀░▀▀▀░▀▀▀░░▀░░▀▀▀░▀░▀░▀▀▀░░░▀░░░▀▀▀░▀▀▀░▀▀░░▀▀▀` + "\n")
-	fmt.Printf("»»»» collection interval %v...\n", reqInterval)
-	for range ticker.C {
-		err := scrapeFeeds(s)
+	s.log.Info("aggregator started", "interval", reqInterval, "user", user.Name)
+	// The first cycle runs straight away so /readyz doesn't wait an interval.
+	for ; ; <-ticker.C {
+		if lock != nil {
+			if err := lock.check(); err != nil {
+				return err
+			}
+		}
+		clean, err := scrapeFeeds(s)
 		if err != nil {
-			return err
+			// A feed failing to fetch or parse is recorded against the feed
+			// and counted in scrapeFeeds, only database errors come back here.
+			s.metrics.errors.WithLabelValues(errorSourceDB).Inc()
+			s.log.Error("fetch cycle failed, retrying next interval", "error", err)
+			continue
 		}
+		s.metrics.cycleCompleted(clean)
 	}
-	return nil
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
@@ -187,6 +254,17 @@ func handlerFeeds(s *state, cmd command) error {
 	if err != nil {
 		return fmt.Errorf("error: could not fetch feeds, maybe there are none? -> %w", err)
 	}
+	if s.output != outputTable {
+		records := make([]feedRecord, 0, len(feeds))
+		for i := range feeds {
+			name, err := s.db.GetUserByID(context.Background(), feeds[i].UserID)
+			if err != nil {
+				return fmt.Errorf("error: issue retrieving user name for feed record -> %w", err)
+			}
+			records = append(records, newFeedRecord(feeds[i], name))
+		}
+		return writeRecords(s.output, records)
+	}
 	if feeds == nil {
 		return fmt.Errorf("error: there are no feeds to show! -> %w", err)
 	}
@@ -207,11 +285,47 @@ func handlerFeeds(s *state, cmd command) error {
 		fmt.Printf("Name » %v\n", feeds[i].Name)
 		fmt.Printf("Url » %v\n", feeds[i].Url)
 		fmt.Printf("Added by » %v\n", name)
+		if feeds[i].AuthType.Valid {
+			fmt.Printf("Auth » %v\n", describeAuth(feeds[i]))
+		}
+		headers, err := s.db.GetFeedHeaders(context.Background(), feeds[i].ID)
+		if err != nil {
+			return fmt.Errorf("error: issue retrieving headers for feed record -> %w", err)
+		}
+		for _, h := range headers {
+			fmt.Printf("Header » %v: %v\n", h.Name, maskSecret(h.Value))
+		}
+		if feeds[i].BytesDecoded > 0 {
+			saved := 100 - feeds[i].BytesTransferred*100/feeds[i].BytesDecoded
+			fmt.Printf("Transferred » %v for %v of feed (%d%% saved)\n", formatBytes(feeds[i].BytesTransferred), formatBytes(feeds[i].BytesDecoded), saved)
+		}
+		if feeds[i].DeadAt.Valid {
+			fmt.Printf("Status » dead since %v\n", feeds[i].DeadAt.Time.Format(time.DateTime))
+		}
+		if feeds[i].LastError.Valid {
+			fmt.Printf("Last error » %v\n", feeds[i].LastError.String)
+		}
+		if feeds[i].NextFetchAt.Valid && !feeds[i].DeadAt.Valid {
+			fmt.Printf("Next try » %v (backing off, %d consecutive failures)\n", feeds[i].NextFetchAt.Time.Format(time.DateTime), feeds[i].ConsecutiveFailures)
+		}
 	}
 	fmt.Printf("\n")
 	return nil
 }
 
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func handlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
 		return fmt.Errorf("error: what are you trying to follow? Use 'follow url' to follow")
@@ -244,6 +358,18 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 	if err != nil {
 		return fmt.Errorf("error: issue fetching follows for user from database -> %w", err)
 	}
+	if s.output != outputTable {
+		records := make([]followRecord, 0, len(follows))
+		for i := range follows {
+			records = append(records, followRecord{
+				FeedID: follows[i].FeedID,
+				Name:   follows[i].FeedName,
+				URL:    follows[i].Url,
+				Unread: follows[i].Unread,
+			})
+		}
+		return writeRecords(s.output, records)
+	}
 	tot := len(follows)
 	fmt.Printf(`
 ░█▀▀░█▀█░█░░░█░░░█▀█░█░█░▀█▀░█▀█░█▀▀
@@ -253,7 +379,8 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		num := i + 1
 		fmt.Printf("»»»» %v of %v\n", num, tot)
 		fmt.Printf("- %v\n", follows[i].FeedName)
-		fmt.Printf("- %v\n\n", follows[i].Url)
+		fmt.Printf("- %v\n", follows[i].Url)
+		fmt.Printf("- %d unread\n\n", follows[i].Unread)
 	}
 	return nil
 }
@@ -278,38 +405,129 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("browse")
+	all := fs.Bool("all", false, "include posts already read")
+	page := fs.Int("page", 1, "page of posts to show, counting from 1")
+	after := fs.String("after", "", "cursor printed at the bottom of the previous page")
+	feed := fs.String("feed", "", "only show posts from this feed name or url")
+	since := fs.String("since", "", "only show posts published since this date or duration ago")
+	until := fs.String("until", "", "only show posts published before this date or duration ago")
+	match := fs.String("match", "", "only show posts with this text in the title or description")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
 	var limit int32
-	if len(cmd.args) < 1 {
+	if len(args) < 1 {
 		limit = 2
 	} else {
-		l, err := strconv.ParseInt(cmd.args[0], 10, 32)
+		l, err := strconv.ParseInt(args[0], 10, 32)
 		if err != nil {
 			return fmt.Errorf("error: limit range on posts command unrecognized or invalid -> %w", err)
 		}
 		limit = int32(l)
 	}
+	if *page < 1 {
+		return fmt.Errorf("error: page must be 1 or more")
+	}
 	postsToFetch := database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  limit,
+		UserID:      user.ID,
+		IncludeRead: *all,
+		MaxPosts:    limit,
+		SkipPosts:   int32(*page-1) * limit,
+	}
+	if *feed != "" {
+		feedID, err := followedFeedID(s, user, *feed)
+		if err != nil {
+			return err
+		}
+		postsToFetch.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	if *since != "" {
+		t, err := parseTimeFlag(*since)
+		if err != nil {
+			return err
+		}
+		postsToFetch.Since = sql.NullTime{Time: t, Valid: true}
+	}
+	if *until != "" {
+		t, err := parseTimeFlag(*until)
+		if err != nil {
+			return err
+		}
+		postsToFetch.Until = sql.NullTime{Time: t, Valid: true}
+	}
+	if *match != "" {
+		postsToFetch.Match = sql.NullString{String: escapeLike(*match), Valid: true}
+	}
+	if *after != "" {
+		if *page > 1 {
+			return fmt.Errorf("error: use either --page or --after, not both")
+		}
+		at, id, err := decodeCursor(*after)
+		if err != nil {
+			return err
+		}
+		postsToFetch.AfterAt = sql.NullTime{Time: at, Valid: true}
+		postsToFetch.AfterID = uuid.NullUUID{UUID: id, Valid: true}
 	}
 	posts, err := s.db.GetPostsForUser(context.Background(), postsToFetch)
 	if err != nil {
 		return fmt.Errorf("error: could not fetch posts -> %w", err)
 	}
+	ids := make([]uuid.UUID, 0, len(posts))
+	for i := range posts {
+		ids = append(ids, posts[i].ID)
+	}
+	handles, err := postHandles(s, user, ids)
+	if err != nil {
+		return err
+	}
+	if s.output != outputTable {
+		records := make([]postRecord, 0, len(posts))
+		for i := range posts {
+			records = append(records, newPostRecord(posts[i], handles[posts[i].ID]))
+		}
+		return writeRecords(s.output, records)
+	}
 	fmt.Printf(`
 ░█▀█░█▀▀░█░█░░░█▀█░█▀█░█▀▀░▀█▀░█▀▀
 ░█░█░█▀▀░█▄█░░░█▀▀░█░█░▀▀█░░█░░▀▀█
 ░▀░▀░▀▀▀░▀░▀░░░▀░░░▀▀▀░▀▀▀░░▀░░▀▀▀` + "\n\n")
+	if len(posts) == 0 && !*all {
+		fmt.Printf("»»»» nothing unread, use 'browse --all' to include read posts\n\n")
+	}
 	for i := range posts {
 		fmt.Println(strings.Repeat("◈", 34))
-		fmt.Printf("»»»» %v\n", posts[i].Title)
+		if posts[i].ReadAt.Valid {
+			fmt.Printf("»»»» [%v] %v (read)\n", handles[posts[i].ID], posts[i].Title)
+		} else {
+			fmt.Printf("»»»» [%v] %v\n", handles[posts[i].ID], posts[i].Title)
+		}
 		fmt.Printf("»»» %v\n", posts[i].PublishedAt)
 		fmt.Printf("»» %v\n", posts[i].Url)
 		fmt.Printf("» %v\n\n", posts[i].Description)
 	}
+	if len(posts) > 0 && len(posts) == int(limit) {
+		last := posts[len(posts)-1]
+		next := fmt.Sprintf("browse %d --after %v", limit, encodeCursor(last.SortAt, last.ID))
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "page" || f.Name == "after" {
+				return
+			}
+			next += fmt.Sprintf(" --%v=%v", f.Name, shellQuote(f.Value.String()))
+		})
+		fmt.Printf("»»»» next page » %v\n", next)
+	}
 	return nil
 }
 
+// escapeLike escapes the wildcards in text so it matches literally inside a
+// LIKE pattern using backslash as the escape character.
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
+
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
 		user, err := s.db.GetUser(context.Background(), s.cfg.UserName)
@@ -329,6 +547,7 @@ func newCommands() *commands {
 func main() {
 	var err error
 	state := new(state)
+	state.log = slog.Default()
 	state.cfg, err = config.Read()
 	if err != nil {
 		fmt.Printf("%v\n", err)
@@ -337,6 +556,7 @@ func main() {
 	if err != nil {
 		fmt.Printf("%v\n", err)
 	}
+	state.sqlDB = db
 	state.db = database.New(db)
 
 	coms := newCommands()
@@ -347,19 +567,37 @@ func main() {
 	coms.register("agg", middlewareLoggedIn(handlerAgg))
 	coms.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	coms.register("feeds", handlerFeeds)
+	coms.register("health", handlerHealth)
 	coms.register("follow", middlewareLoggedIn(handlerFollow))
 	coms.register("following", middlewareLoggedIn(handlerFollowing))
 	coms.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	coms.register("browse", middlewareLoggedIn(handlerBrowse))
+	coms.register("read", middlewareLoggedIn(handlerRead))
+	coms.register("unread", middlewareLoggedIn(handlerUnread))
+	coms.register("mark-all-read", middlewareLoggedIn(handlerMarkAllRead))
+	coms.register("star", middlewareLoggedIn(handlerStar))
+	coms.register("unstar", middlewareLoggedIn(handlerUnstar))
+	coms.register("starred", middlewareLoggedIn(handlerStarred))
+	coms.register("search", middlewareLoggedIn(handlerSearch))
+	coms.register("tui", middlewareLoggedIn(handlerTUI))
+	coms.register("shell", coms.handlerShell)
+	coms.register("setauth", middlewareLoggedIn(handlerSetAuth))
+	coms.register("setheader", middlewareLoggedIn(handlerSetHeader))
+	coms.register("unsetheader", middlewareLoggedIn(handlerUnsetHeader))
 
-	args := os.Args
-	if len(args) < 2 {
+	output, args, err := extractOutputFlag(os.Args[1:], outputTable)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	state.output = output
+	if len(args) < 1 {
 		fmt.Printf("error: missing command arguments, use gator 'command_name'\n")
 		os.Exit(1)
 	}
 	usercom := command{
-		name: strings.ToLower(args[1]),
-		args: args[2:],
+		name: strings.ToLower(args[0]),
+		args: args[1:],
 	}
 	_, ok := coms.commands[usercom.name]
 	if !ok {
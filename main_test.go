@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"postgres", "postgres"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`C:\path`, `C:\\path`},
+		{`%_\`, `\%\_\\`},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.text); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// aggMetrics are the counters and histograms agg exposes on --metrics-addr.
+type aggMetrics struct {
+	registry       *prometheus.Registry
+	fetches        *prometheus.CounterVec
+	fetchDuration  *prometheus.HistogramVec
+	posts          *prometheus.CounterVec
+	feedsDue       prometheus.Gauge
+	feedsProcessed prometheus.Counter
+	errors         *prometheus.CounterVec
+	lastSuccess    prometheus.Gauge
+	// lastSuccessAt is unix nanoseconds of the last good cycle, or of startup
+	// until there has been one, so the age keeps growing if agg never succeeds.
+	lastSuccessAt atomic.Int64
+	// lastCycleAt is unix nanoseconds of the last completed cycle, whether or
+	// not its feed failed, zero until one has completed, for /readyz.
+	lastCycleAt atomic.Int64
+}
+
+func newAggMetrics() *aggMetrics {
+	m := &aggMetrics{
+		registry: prometheus.NewRegistry(),
+		fetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_fetches_total",
+			Help: "Feed fetches by outcome: success, http_error, rate_limited, gone or error.",
+		}, []string{"outcome"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gator_fetch_duration_seconds",
+			Help:    "Time taken to fetch a feed, by host.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host"}),
+		posts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_posts_total",
+			Help: "Feed items processed by result: inserted, skipped or failed.",
+		}, []string{"result"}),
+		feedsDue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gator_feeds_due",
+			Help: "Feeds due for fetching at the start of the last cycle.",
+		}),
+		feedsProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gator_feeds_processed_total",
+			Help: "Feeds fetched, successfully or not.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_errors_total",
+			Help: "Errors during aggregation by source: fetch, parse or db.",
+		}, []string{"source"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gator_last_successful_cycle_timestamp_seconds",
+			Help: "Unix time the last fetch cycle completed without error, a feed failing to fetch or save counts as an error.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.fetches,
+		m.fetchDuration,
+		m.posts,
+		m.feedsDue,
+		m.feedsProcessed,
+		m.errors,
+		m.lastSuccess,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "gator_seconds_since_last_successful_cycle",
+			Help: "Seconds since the last fetch cycle completed without error, a feed failing to fetch or save counts as an error.",
+		}, m.secondsSinceSuccess),
+	)
+	m.lastSuccessAt.Store(time.Now().UnixNano())
+	return m
+}
+
+func (m *aggMetrics) secondsSinceSuccess() float64 {
+	return m.sinceLastSuccess().Seconds()
+}
+
+func (m *aggMetrics) sinceLastSuccess() time.Duration {
+	return time.Since(time.Unix(0, m.lastSuccessAt.Load()))
+}
+
+// sinceLastCycle is how long ago the last good cycle completed, false if none
+// has completed yet.
+func (m *aggMetrics) sinceLastCycle() (time.Duration, bool) {
+	at := m.lastCycleAt.Load()
+	if at == 0 {
+		return 0, false
+	}
+	return time.Since(time.Unix(0, at)), true
+}
+
+func (m *aggMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// cycleCompleted records a fetch cycle that ran to the end, counting it as a
+// success only when clean, that is its feed was fetched and saved or no feed
+// was due.
+func (m *aggMetrics) cycleCompleted(clean bool) {
+	now := time.Now()
+	m.lastCycleAt.Store(now.UnixNano())
+	if !clean {
+		return
+	}
+	m.lastSuccessAt.Store(now.UnixNano())
+	m.lastSuccess.Set(float64(now.Unix()))
+}
+
+func (m *aggMetrics) observeFetch(host string, elapsed time.Duration, err error) {
+	m.feedsProcessed.Inc()
+	m.fetchDuration.WithLabelValues(host).Observe(elapsed.Seconds())
+	m.fetches.WithLabelValues(fetchOutcome(err)).Inc()
+}
+
+// Sources of errors counted by gator_errors_total.
+const (
+	errorSourceFetch = "fetch"
+	errorSourceParse = "parse"
+	errorSourceDB    = "db"
+)
+
+// fetchErrorSource tells a feed that could not be fetched from one that was
+// fetched but could not be read as a feed.
+func fetchErrorSource(err error) string {
+	var parseErr *parseError
+	if errors.As(err, &parseErr) {
+		return errorSourceParse
+	}
+	return errorSourceFetch
+}
+
+func fetchOutcome(err error) string {
+	if err == nil {
+		return "success"
+	}
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return "error"
+	}
+	switch statusErr.StatusCode {
+	case http.StatusGone:
+		return "gone"
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return "rate_limited"
+	}
+	return "http_error"
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFetchErrorClassification(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantSource  string
+		wantOutcome string
+	}{
+		{"bad xml", &parseError{errors.New("error: invalid xml")}, errorSourceParse, "error"},
+		{"wrapped parse error", fmt.Errorf("wrapped -> %w", &parseError{errors.New("not a feed")}), errorSourceParse, "error"},
+		{"not found", &statusError{StatusCode: http.StatusNotFound}, errorSourceFetch, "http_error"},
+		{"gone", &statusError{StatusCode: http.StatusGone}, errorSourceFetch, "gone"},
+		{"rate limited", &statusError{StatusCode: http.StatusTooManyRequests}, errorSourceFetch, "rate_limited"},
+		{"unavailable", &statusError{StatusCode: http.StatusServiceUnavailable}, errorSourceFetch, "rate_limited"},
+		{"connection refused", errors.New("error: response -> connection refused"), errorSourceFetch, "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fetchErrorSource(tt.err); got != tt.wantSource {
+				t.Errorf("fetchErrorSource() = %v, want %v", got, tt.wantSource)
+			}
+			if got := fetchOutcome(tt.err); got != tt.wantOutcome {
+				t.Errorf("fetchOutcome() = %v, want %v", got, tt.wantOutcome)
+			}
+		})
+	}
+	if got := fetchOutcome(nil); got != "success" {
+		t.Errorf("fetchOutcome(nil) = %v, want success", got)
+	}
+}
+
+func TestCycleCompleted(t *testing.T) {
+	tests := []struct {
+		name        string
+		clean       bool
+		wantSuccess bool
+	}{
+		{"clean cycle", true, true},
+		{"feed failed", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newAggMetrics()
+			started := m.lastSuccessAt.Load()
+			time.Sleep(time.Millisecond)
+			m.cycleCompleted(tt.clean)
+			if _, ok := m.sinceLastCycle(); !ok {
+				t.Error("sinceLastCycle() reports no completed cycle")
+			}
+			succeeded := m.lastSuccessAt.Load() != started
+			if gauge := testutil.ToFloat64(m.lastSuccess); (gauge != 0) != tt.wantSuccess || succeeded != tt.wantSuccess {
+				t.Errorf("cycleCompleted(%v) recorded success = %v (gauge %v), want %v", tt.clean, succeeded, gauge, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+// Output formats for listing commands. Table is the decorated text for
+// reading, the others are stable schemas for scripts, one record per row.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+)
+
+// extractOutputFlag reads the global --output (or -o) option from the start
+// of args, before the command name, and returns the chosen format, or def if
+// there is none, with the command and its arguments. Everything from the
+// command name on is left alone, so a command's own arguments are never taken
+// for the option.
+func extractOutputFlag(args []string, def string) (string, []string, error) {
+	format := def
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		if name != "--output" && name != "-output" && name != "-o" {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return "", nil, fmt.Errorf("error: --output needs a format, use table, json, csv or tsv")
+			}
+			args = args[1:]
+			value = args[0]
+		}
+		format = strings.ToLower(value)
+		args = args[1:]
+	}
+	switch format {
+	case outputTable, outputJSON, outputCSV, outputTSV:
+		return format, args, nil
+	}
+	return "", nil, fmt.Errorf("error: unknown output format '%v', use table, json, csv or tsv", format)
+}
+
+// writeRecords prints a slice of record structs to stdout as json, or as csv
+// or tsv with a header row named by each field's json tag.
+func writeRecords(format string, records any) error {
+	v := reflect.ValueOf(records)
+	for i := 0; i < v.Len(); i++ {
+		localizeTimes(v.Index(i))
+	}
+	if format == outputJSON {
+		if v.Len() == 0 {
+			fmt.Println("[]")
+			return nil
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	w := csv.NewWriter(os.Stdout)
+	if format == outputTSV {
+		w.Comma = '\t'
+	}
+	t := v.Type().Elem()
+	header := make([]string, t.NumField())
+	for i := range header {
+		header[i], _, _ = strings.Cut(t.Field(i).Tag.Get("json"), ",")
+	}
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("error: could not write output -> %w", err)
+	}
+	for i := 0; i < v.Len(); i++ {
+		row := make([]string, t.NumField())
+		for j := range row {
+			row[j] = fieldText(v.Index(i).Field(j))
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("error: could not write output -> %w", err)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// fieldText formats a record field for a csv cell, nil is left empty. Times
+// use the same layout encoding/json does.
+func fieldText(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// localTime gives a time read from the database its real offset. gator saves
+// local wall clock times in columns without a zone, which lib/pq reads back
+// labelled UTC.
+func localTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// localizeTimes applies localTime to the time fields of a record in place.
+func localizeTimes(record reflect.Value) {
+	for i := 0; i < record.NumField(); i++ {
+		field := record.Field(i)
+		switch t := field.Interface().(type) {
+		case time.Time:
+			field.Set(reflect.ValueOf(localTime(t)))
+		case *time.Time:
+			if t != nil {
+				local := localTime(*t)
+				field.Set(reflect.ValueOf(&local))
+			}
+		}
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+type userRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Current   bool      `json:"current"`
+}
+
+type feedRecord struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	AddedBy             string     `json:"added_by"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	NextFetchAt         *time.Time `json:"next_fetch_at"`
+	DeadAt              *time.Time `json:"dead_at"`
+	LastStatus          *int32     `json:"last_status"`
+	LastError           *string    `json:"last_error"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	AuthType            *string    `json:"auth_type"`
+	BytesTransferred    int64      `json:"bytes_transferred"`
+	BytesDecoded        int64      `json:"bytes_decoded"`
+}
+
+func newFeedRecord(feed database.Feed, addedBy string) feedRecord {
+	return feedRecord{
+		ID:                  feed.ID,
+		Name:                feed.Name,
+		URL:                 feed.Url,
+		AddedBy:             addedBy,
+		CreatedAt:           feed.CreatedAt,
+		UpdatedAt:           feed.UpdatedAt,
+		LastFetchedAt:       nullTime(feed.LastFetchedAt),
+		NextFetchAt:         nullTime(feed.NextFetchAt),
+		DeadAt:              nullTime(feed.DeadAt),
+		LastStatus:          nullInt32(feed.LastStatus),
+		LastError:           nullString(feed.LastError),
+		ConsecutiveFailures: feed.ConsecutiveFailures,
+		AuthType:            nullString(feed.AuthType),
+		BytesTransferred:    feed.BytesTransferred,
+		BytesDecoded:        feed.BytesDecoded,
+	}
+}
+
+type followRecord struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Name   string    `json:"name"`
+	URL    string    `json:"url"`
+	Unread int64     `json:"unread"`
+}
+
+// postRecord is a post listed by browse, cursor can be passed to
+// 'browse --after' to continue after it.
+type postRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReadAt      *time.Time `json:"read_at"`
+	Cursor      string     `json:"cursor"`
+	Handle      int32      `json:"handle"`
+}
+
+func newPostRecord(post database.GetPostsForUserRow, handle int32) postRecord {
+	return postRecord{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: post.Description,
+		PublishedAt: nullTime(post.PublishedAt),
+		CreatedAt:   post.CreatedAt,
+		ReadAt:      nullTime(post.ReadAt),
+		Cursor:      encodeCursor(post.SortAt, post.ID),
+		Handle:      handle,
+	}
+}
+
+type starredRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	PublishedAt *time.Time `json:"published_at"`
+	StarredAt   time.Time  `json:"starred_at"`
+	Handle      int32      `json:"handle"`
+}
+
+func newStarredRecord(post database.GetStarredPostsRow, handle int32) starredRecord {
+	return starredRecord{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		FeedName:    post.FeedName,
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: post.Description,
+		PublishedAt: nullTime(post.PublishedAt),
+		StarredAt:   post.StarredAt,
+		Handle:      handle,
+	}
+}
+
+type searchRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	PublishedAt *time.Time `json:"published_at"`
+	Rank        float32    `json:"rank"`
+	Snippet     string     `json:"snippet"`
+	Handle      int32      `json:"handle"`
+}
+
+func newSearchRecord(post database.SearchPostsRow, handle int32) searchRecord {
+	return searchRecord{
+		ID:          post.ID,
+		FeedName:    post.FeedName,
+		Title:       post.Title,
+		URL:         post.Url,
+		PublishedAt: nullTime(post.PublishedAt),
+		Rank:        post.Rank,
+		Snippet:     normalizeSpaces(post.Snippet),
+		Handle:      handle,
+	}
+}
+
+type healthRecord struct {
+	FeedID        uuid.UUID  `json:"feed_id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	Attempts      int64      `json:"attempts"`
+	Successes     int64      `json:"successes"`
+	AvgLatencyMs  float64    `json:"avg_latency_ms"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+	LastPostAt    *time.Time `json:"last_post_at"`
+	DeadAt        *time.Time `json:"dead_at"`
+	Stale         bool       `json:"stale"`
+}
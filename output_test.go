@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExtractOutputFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantFormat string
+		wantArgs   []string
+		wantErr    bool
+	}{
+		{"none", []string{"browse", "5"}, outputTable, []string{"browse", "5"}, false},
+		{"long with space", []string{"--output", "json", "browse"}, outputJSON, []string{"browse"}, false},
+		{"long with equals", []string{"--output=csv", "feeds"}, outputCSV, []string{"feeds"}, false},
+		{"single dash", []string{"-output", "tsv", "users"}, outputTSV, []string{"users"}, false},
+		{"short", []string{"-o", "JSON", "users"}, outputJSON, []string{"users"}, false},
+		{"last one wins", []string{"-o", "csv", "-o", "json", "users"}, outputJSON, []string{"users"}, false},
+		{"after the command is left alone", []string{"search", "-o"}, outputTable, []string{"search", "-o"}, false},
+		{"command argument", []string{"addfeed", "name", "-o", "json"}, outputTable, []string{"addfeed", "name", "-o", "json"}, false},
+		{"only the option", []string{"-o", "json"}, outputJSON, []string{}, false},
+		{"missing format", []string{"-o"}, "", nil, true},
+		{"unknown format", []string{"-o", "xml", "users"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, args, err := extractOutputFlag(tt.args, outputTable)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractOutputFlag() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if format != tt.wantFormat || !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("extractOutputFlag() = %v, %q, want %v, %q", format, args, tt.wantFormat, tt.wantArgs)
+			}
+		})
+	}
+}
+
+type testRecord struct {
+	Name  string     `json:"name"`
+	At    time.Time  `json:"at"`
+	Maybe *time.Time `json:"maybe"`
+	Count *int32     `json:"count"`
+}
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	ferr := f()
+	os.Stdout = stdout
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	return string(out)
+}
+
+func TestWriteRecords(t *testing.T) {
+	// Times come back from the database as local wall clock labelled UTC.
+	local := time.Local
+	time.Local = time.FixedZone("test", 2*60*60)
+	defer func() { time.Local = local }()
+	at := time.Date(2026, 10, 18, 9, 15, 0, 500000000, time.UTC)
+	count := int32(3)
+	records := []testRecord{
+		{Name: "a, b", At: at, Count: &count},
+		{Name: "tab\there", At: at, Maybe: &at},
+	}
+	wantCSV := "name,at,maybe,count\n" +
+		"\"a, b\",2026-10-18T09:15:00.5+02:00,,3\n" +
+		"tab\there,2026-10-18T09:15:00.5+02:00,2026-10-18T09:15:00.5+02:00,\n"
+	if got := captureStdout(t, func() error { return writeRecords(outputCSV, records) }); got != wantCSV {
+		t.Errorf("csv output = %q, want %q", got, wantCSV)
+	}
+	wantTSV := "name\tat\tmaybe\tcount\n" +
+		"a, b\t2026-10-18T09:15:00.5+02:00\t\t3\n" +
+		"\"tab\there\"\t2026-10-18T09:15:00.5+02:00\t2026-10-18T09:15:00.5+02:00\t\n"
+	if got := captureStdout(t, func() error { return writeRecords(outputTSV, records) }); got != wantTSV {
+		t.Errorf("tsv output = %q, want %q", got, wantTSV)
+	}
+
+	out := captureStdout(t, func() error { return writeRecords(outputJSON, records) })
+	var decoded []map[string]any
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("json output does not parse: %v\n%v", err, out)
+	}
+	if decoded[0]["at"] != "2026-10-18T09:15:00.5+02:00" || decoded[0]["maybe"] != nil || decoded[0]["count"] != float64(3) {
+		t.Errorf("json output = %v", decoded[0])
+	}
+
+	empty := captureStdout(t, func() error { return writeRecords(outputJSON, []testRecord{}) })
+	if empty != "[]\n" {
+		t.Errorf("empty json output = %q, want []", empty)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+// minIDPrefix is the fewest characters of a post id accepted as a reference.
+const minIDPrefix = 4
+
+// defaultReadWidth is the column article text is wrapped at.
+const defaultReadWidth = 80
+
+// handlerRead shows a post's full article and marks it read. The article is
+// fetched and extracted the first time, after that the copy saved with the
+// post is shown so it can be read offline. When the article can't be fetched
+// the saved copy is shown instead, or the feed's description if there is none.
+func handlerRead(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("read")
+	refresh := fs.Bool("refresh", false, "fetch the article again instead of using the saved copy")
+	width := fs.Int("width", defaultReadWidth, "column to wrap the article at")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return fmt.Errorf("error: no post provided, use 'read n' with the number shown by browse")
+	}
+	post, err := resolvePost(s, user, args[0])
+	if err != nil {
+		return err
+	}
+	content := post.Content.String
+	var fetchErr error
+	if !post.Content.Valid || *refresh {
+		var fetched string
+		fetched, fetchErr = fetchArticle(s, post.ID, post.Url)
+		switch {
+		case fetchErr == nil:
+			content = fetched
+		case !post.Content.Valid:
+			content = post.Description
+		}
+	}
+	fmt.Println(strings.Repeat("◈", 34))
+	fmt.Printf("»»»» %v\n", post.Title)
+	if post.PublishedAt.Valid {
+		fmt.Printf("»»» %v\n", post.PublishedAt.Time.Format(time.DateTime))
+	}
+	fmt.Printf("»» %v\n", post.Url)
+	fmt.Println(strings.Repeat("◈", 34))
+	fmt.Printf("\n%v\n\n", wrapText(content, max(20, *width)))
+	if fetchErr != nil && post.Content.Valid {
+		fmt.Printf("»»»» could not fetch the article again, showing the saved copy -> %v\n", fetchErr)
+	} else if fetchErr != nil {
+		fmt.Printf("»»»» could not fetch the article, showing the feed's description -> %v\n", fetchErr)
+	}
+	read := database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now(),
+	}
+	if err := s.db.MarkPostRead(context.Background(), read); err != nil {
+		return fmt.Errorf("error: could not mark post as read -> %w", err)
+	}
+	return nil
+}
+
+// fetchArticle downloads the page a post links to, extracts the article and
+// saves it with the post.
+func fetchArticle(s *state, postID uuid.UUID, url string) (string, error) {
+	if err := s.ensureFetcher(); err != nil {
+		return "", err
+	}
+	page, _, err := s.fetcher.fetchPage(context.Background(), url)
+	if err != nil {
+		return "", err
+	}
+	content, err := extractArticle(page)
+	if err != nil {
+		return "", err
+	}
+	saved := database.SetPostContentParams{
+		Content:          sql.NullString{String: content, Valid: true},
+		ContentFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:               postID,
+	}
+	if err := s.db.SetPostContent(context.Background(), saved); err != nil {
+		return "", fmt.Errorf("error: could not save article -> %w", err)
+	}
+	return content, nil
+}
+
+func handlerUnread(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("error: no post provided, use 'unread n' with the number shown by browse")
+	}
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	unread := database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	if _, err := s.db.MarkPostUnread(context.Background(), unread); err != nil {
+		return fmt.Errorf("error: could not mark post as unread -> %w", err)
+	}
+	fmt.Printf("»»»» marked unread: %v\n", post.Title)
+	return nil
+}
+
+func handlerMarkAllRead(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("mark-all-read")
+	feed := fs.String("feed", "", "only mark posts from this feed name or url")
+	before := fs.String("before", "", "only mark posts published before this date or duration ago")
+	if _, err := parseFlags(fs, cmd.args); err != nil {
+		return err
+	}
+	markAll := database.MarkAllReadParams{
+		ReadAt: time.Now(),
+		UserID: user.ID,
+	}
+	if *feed != "" {
+		feedID, err := followedFeedID(s, user, *feed)
+		if err != nil {
+			return err
+		}
+		markAll.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	if *before != "" {
+		t, err := parseTimeFlag(*before)
+		if err != nil {
+			return err
+		}
+		markAll.Before = sql.NullTime{Time: t, Valid: true}
+	}
+	n, err := s.db.MarkAllRead(context.Background(), markAll)
+	if err != nil {
+		return fmt.Errorf("error: could not mark posts as read -> %w", err)
+	}
+	fmt.Printf("»»»» %d posts marked read\n", n)
+	return nil
+}
+
+// resolvePost finds the post ref refers to among the feeds the user follows
+// and the posts they starred, by the user's number for it as printed by
+// browse, then by its url, full id or the start of its id. A number is always
+// taken as the user's number for a post when they have one by it, so it keeps
+// meaning the same post however many ids come to start with its digits.
+func resolvePost(s *state, user database.User, ref string) (database.FindPostsRow, error) {
+	byHandle, err := postIDByHandle(s, user, ref)
+	if err != nil {
+		return database.FindPostsRow{}, err
+	}
+	lookup := ref
+	if byHandle.Valid {
+		lookup = byHandle.UUID.String()
+	}
+	posts, err := findPosts(s, user, lookup)
+	if err != nil {
+		return database.FindPostsRow{}, err
+	}
+	switch len(posts) {
+	case 0:
+		return database.FindPostsRow{}, fmt.Errorf("error: no post you follow or starred matches '%v'", ref)
+	case 1:
+		return posts[0], nil
+	default:
+		return database.FindPostsRow{}, fmt.Errorf("error: '%v' matches more than one post, use more of the id", ref)
+	}
+}
+
+// postIDByHandle looks ref up as one of the user's post numbers, returning
+// no id when it isn't a number or the user has no post with it.
+func postIDByHandle(s *state, user database.User, ref string) (uuid.NullUUID, error) {
+	handle, err := strconv.ParseInt(ref, 10, 32)
+	if err != nil || handle <= 0 {
+		return uuid.NullUUID{}, nil
+	}
+	lookup := database.GetPostIDByHandleParams{
+		UserID: user.ID,
+		Handle: int32(handle),
+	}
+	postID, err := s.db.GetPostIDByHandle(context.Background(), lookup)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, nil
+	}
+	if err != nil {
+		return uuid.NullUUID{}, fmt.Errorf("error: could not look up post -> %w", err)
+	}
+	return uuid.NullUUID{UUID: postID, Valid: true}, nil
+}
+
+// findPosts returns the posts the user follows or starred whose url is ref
+// or whose id starts with it.
+func findPosts(s *state, user database.User, ref string) ([]database.FindPostsRow, error) {
+	find := database.FindPostsParams{
+		Ref:    ref,
+		UserID: user.ID,
+	}
+	if isIDPrefix(ref) {
+		find.IdPrefix = sql.NullString{String: strings.ToLower(ref), Valid: true}
+	}
+	posts, err := s.db.FindPosts(context.Background(), find)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not look up post -> %w", err)
+	}
+	return posts, nil
+}
+
+func isIDPrefix(ref string) bool {
+	if len(ref) < minIDPrefix {
+		return false
+	}
+	for _, r := range strings.ToLower(ref) {
+		if !strings.ContainsRune("0123456789abcdef-", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// postHandles numbers posts for the user so commands can refer to them by a
+// short handle. Posts keep the number they were first given, new ones are
+// numbered on from the user's highest in the order ids lists them. The user's
+// handles are locked while numbering so two listings at once can't both take
+// the next number.
+func postHandles(s *state, user database.User, ids []uuid.UUID) (map[uuid.UUID]int32, error) {
+	tx, err := s.sqlDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not begin transaction -> %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+	if err := qtx.LockPostHandles(context.Background(), user.ID); err != nil {
+		return nil, fmt.Errorf("error: could not lock post numbers -> %w", err)
+	}
+	ensure := database.EnsurePostHandlesParams{
+		PostIds: ids,
+		UserID:  user.ID,
+	}
+	rows, err := qtx.EnsurePostHandles(context.Background(), ensure)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not number posts -> %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error: could not commit post numbers -> %w", err)
+	}
+	handles := make(map[uuid.UUID]int32, len(rows))
+	for i := range rows {
+		handles[rows[i].PostID] = rows[i].Handle
+	}
+	return handles, nil
+}
+
+// followedFeedID finds a feed the user follows by name or url.
+func followedFeedID(s *state, user database.User, ref string) (uuid.UUID, error) {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error: issue fetching follows for user from database -> %w", err)
+	}
+	for i := range follows {
+		if follows[i].Url == ref || strings.EqualFold(follows[i].FeedName, ref) {
+			return follows[i].FeedID, nil
+		}
+	}
+	return uuid.Nil, fmt.Errorf("error: %v follows no feed named or at '%v'", user.Name, ref)
+}
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"context"
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
-	"net/http"
 	"regexp"
 	"strings"
-	"time"
 )
 
+// maxXMLDepth is far deeper than any real feed nests its elements.
+const maxXMLDepth = 64
+
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
@@ -28,28 +30,33 @@ type RSSItem struct {
 	PubDate     string `xml:"pubDate"`
 }
 
-func fetchFeed(ctx context.Context, feedurl string) (*RSSFeed, error) {
-	feed := new(RSSFeed)
-	req, err := http.NewRequestWithContext(ctx, "GET", feedurl, nil)
-	if err != nil {
-		return feed, fmt.Errorf("error: request -> %w", err)
-	}
-	req.Header.Set("User-Agent", "gator")
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return feed, fmt.Errorf("error: response -> %w", err)
-	}
-	defer res.Body.Close()
-	data, err := io.ReadAll(res.Body)
-	if err != nil {
-		return feed, fmt.Errorf("error: Reading response -> %w", err)
-	}
-	if err := xml.Unmarshal(data, feed); err != nil {
-		return feed, fmt.Errorf("error: Unmarshal -> %w", err)
+// checkXML walks the document before it is unmarshalled, rejecting feeds that
+// declare their own entities or nest deeper than maxXMLDepth.
+func checkXML(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error: invalid xml -> %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth > maxXMLDepth {
+				return fmt.Errorf("error: xml nested deeper than %d elements", maxXMLDepth)
+			}
+		case xml.EndElement:
+			depth--
+		case xml.Directive:
+			if bytes.Contains(t, []byte("<!ENTITY")) {
+				return fmt.Errorf("error: xml declares its own entities, refusing to parse")
+			}
+		}
 	}
-	feed.unescapeHTML()
-	return feed, nil
 }
 
 func (r *RSSFeed) unescapeHTML() {
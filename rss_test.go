@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"plain feed", `<?xml version="1.0"?><rss><channel><item><title>a &amp; b</title></item></channel></rss>`, false},
+		{"doctype without entities", `<!DOCTYPE rss><rss></rss>`, false},
+		{"internal entity", `<!DOCTYPE rss [<!ENTITY a "aaaa">]><rss>&a;</rss>`, true},
+		{"billion laughs", `<!DOCTYPE lolz [<!ENTITY lol "lol"><!ENTITY lol2 "&lol;&lol;">]><lolz>&lol2;</lolz>`, true},
+		{"external entity", `<!DOCTYPE rss [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><rss>&xxe;</rss>`, true},
+		{"at max depth", strings.Repeat("<a>", maxXMLDepth) + strings.Repeat("</a>", maxXMLDepth), false},
+		{"too deep", strings.Repeat("<a>", maxXMLDepth+1) + strings.Repeat("</a>", maxXMLDepth+1), true},
+		{"malformed", `<rss><channel></rss`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkXML([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkXML() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
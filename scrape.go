@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -13,21 +16,46 @@ import (
 	"github.com/lib/pq"
 )
 
-func scrapeFeeds(s *state) error {
-	nextfeed, err := s.db.GetNextFeedToFetch(context.Background())
+// scrapeFeeds fetches the next feed due and saves its posts. It reports
+// whether the cycle went cleanly, false when the feed failed and was backed
+// off, the error is only for failures it could not record against the feed.
+func scrapeFeeds(s *state) (bool, error) {
+	due, err := s.db.CountDueFeeds(context.Background(), time.Now())
 	if err != nil {
-		return fmt.Errorf("error: could not retrieve next feed to fetch -> %w", err)
+		return false, fmt.Errorf("error: could not count feeds due for fetching -> %w", err)
+	}
+	s.metrics.feedsDue.Set(float64(due))
+	nextfeed, err := s.db.GetNextFeedToFetch(context.Background(), time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		s.log.Debug("no feeds due for fetching")
+		return true, nil
 	}
-	fmt.Printf(`
-
-░█▀▀░█▀▀░▀█▀░█▀▀░█░█░▀█▀░█▀█░█▀▀░░░█▀▀░█▀▀░█▀▀░█▀▄
-░█▀▀░█▀▀░░█░░█░░░█▀█░░█░░█░█░█░█░░░█▀▀░█▀▀░█▀▀░█░█
-░▀░░░▀▀▀░░▀░░▀▀▀░▀░▀░▀▀▀░▀░▀░▀▀▀░░░▀░░░▀▀▀░▀▀▀░▀▀░
-»»»» %v
-`+"\n", nextfeed.Url)
-	RSS, err := fetchFeed(context.Background(), nextfeed.Url)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("error: could not retrieve next feed to fetch -> %w", err)
+	}
+	log := s.log.With("feed", nextfeed.Url)
+	log.Debug("fetching feed")
+	creds, err := loadFeedCredentials(s, nextfeed)
+	if err != nil {
+		return false, err
+	}
+	started := time.Now()
+	RSS, info, err := s.fetcher.fetchFeed(context.Background(), nextfeed.Url, creds)
+	elapsed := time.Since(started)
+	s.metrics.observeFetch(feedHost(nextfeed.Url), elapsed, err)
+	attempt := database.CreateFetchLogParams{
+		ID:         uuid.New(),
+		FeedID:     nextfeed.ID,
+		StartedAt:  started,
+		DurationMs: int32(elapsed.Milliseconds()),
+		Bytes:      info.WireBytes,
+	}
+	if info.StatusCode != 0 {
+		attempt.HttpStatus = sql.NullInt32{Int32: int32(info.StatusCode), Valid: true}
+	}
+	if err != nil {
+		s.metrics.errors.WithLabelValues(fetchErrorSource(err)).Inc()
+		return false, recordFailedAttempt(s, log, nextfeed, attempt, err)
 	}
 	fetched := database.MarkFeedFetchedParams{
 		UpdatedAt: time.Now(),
@@ -35,41 +63,243 @@ func scrapeFeeds(s *state) error {
 			Time:  time.Now(),
 			Valid: true,
 		},
-		ID: nextfeed.ID,
+		LastStatus:       attempt.HttpStatus,
+		BytesTransferred: info.WireBytes,
+		BytesDecoded:     info.BodyBytes,
+		ID:               nextfeed.ID,
 	}
-	if err := s.db.MarkFeedFetched(context.Background(), fetched); err != nil {
-		return fmt.Errorf("error: could not mark feed as fetched -> %w", err)
+	trackRedirect(&fetched, nextfeed, info)
+	posts := database.CreatePostsParams{
+		Now:    time.Now(),
+		FeedID: nextfeed.ID,
 	}
-
 	for i := range RSS.Channel.Item {
 		pubDate, err := parsePubDate(RSS.Channel.Item[i].PubDate)
 		if err != nil {
+			log.Debug("unrecognised pubDate, using current time", "title", RSS.Channel.Item[i].Title, "pubDate", RSS.Channel.Item[i].PubDate)
 		}
-		postParams := database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       RSS.Channel.Item[i].Title,
-			Url:         RSS.Channel.Item[i].Link,
-			Description: RSS.Channel.Item[i].Description,
-			PublishedAt: sql.NullTime{
-				Time:  pubDate,
-				Valid: true,
-			},
-			FeedID: nextfeed.ID,
+		posts.Ids = append(posts.Ids, uuid.New())
+		posts.Titles = append(posts.Titles, RSS.Channel.Item[i].Title)
+		posts.Urls = append(posts.Urls, RSS.Channel.Item[i].Link)
+		posts.Descriptions = append(posts.Descriptions, RSS.Channel.Item[i].Description)
+		posts.PublishedAt = append(posts.PublishedAt, pubDate)
+	}
+	attempt.ItemsSeen = int32(len(RSS.Channel.Item))
+	inserted, err := saveFetch(s, posts, fetched, &attempt)
+	if err != nil {
+		// Backed off like a failed fetch, a feed that can never be saved
+		// would otherwise stay first in line and starve the others.
+		s.metrics.posts.WithLabelValues("failed").Add(float64(len(posts.Urls)))
+		s.metrics.errors.WithLabelValues(errorSourceDB).Inc()
+		attempt.NewPosts = 0
+		return false, recordFailedAttempt(s, log, nextfeed, attempt, err)
+	}
+	logInserted(log, posts.Urls, inserted)
+	s.metrics.posts.WithLabelValues("inserted").Add(float64(len(inserted)))
+	s.metrics.posts.WithLabelValues("skipped").Add(float64(len(posts.Urls) - len(inserted)))
+	if fetched.RedirectCount >= permanentRedirectThreshold {
+		if err := moveFeedURL(s, log, nextfeed, creds, info.FinalURL); err != nil {
+			return false, err
 		}
-		_, err = s.db.CreatePost(context.Background(), postParams)
-		if err != nil {
-			handleInsertErr(err)
-		} else {
-			fmt.Printf("Adding record for -> %v\n", RSS.Channel.Item[i].Title)
+	}
+	log.Info("feed fetched",
+		"status", info.StatusCode,
+		"duration", elapsed.Round(time.Millisecond),
+		"bytes", info.WireBytes,
+		"decoded_bytes", info.BodyBytes,
+		"items", attempt.ItemsSeen,
+		"new_posts", attempt.NewPosts,
+	)
+	return true, nil
+}
+
+// saveFetch inserts a fetched feed's posts, marks it fetched and records the
+// attempt in one transaction, so a failure part way leaves the feed due with
+// none of its items saved rather than fetched with some missing. It returns
+// the urls of the posts that were new.
+func saveFetch(s *state, posts database.CreatePostsParams, fetched database.MarkFeedFetchedParams, attempt *database.CreateFetchLogParams) ([]string, error) {
+	tx, err := s.sqlDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not begin transaction -> %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+	inserted, err := qtx.CreatePosts(context.Background(), posts)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not insert posts -> %w", err)
+	}
+	if err := qtx.MarkFeedFetched(context.Background(), fetched); err != nil {
+		return nil, fmt.Errorf("error: could not mark feed as fetched -> %w", err)
+	}
+	attempt.NewPosts = int32(len(inserted))
+	if err := qtx.CreateFetchLog(context.Background(), *attempt); err != nil {
+		return nil, fmt.Errorf("error: could not record fetch attempt -> %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error: could not commit fetched feed -> %w", err)
+	}
+	return inserted, nil
+}
+
+// logInserted logs each post as added or skipped when already recorded.
+func logInserted(log *slog.Logger, urls, inserted []string) {
+	if !log.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	added := make(map[string]bool, len(inserted))
+	for _, u := range inserted {
+		added[u] = true
+	}
+	for _, u := range urls {
+		if added[u] {
+			log.Debug("post added", "url", u)
+			continue
+		}
+		log.Debug("post skipped, already recorded in previous fetch", "url", u)
+	}
+}
+
+// recordFailedAttempt logs a fetch attempt that failed with err and backs
+// the feed off.
+func recordFailedAttempt(s *state, log *slog.Logger, feed database.GetNextFeedToFetchRow, attempt database.CreateFetchLogParams, err error) error {
+	attempt.Error = sql.NullString{String: err.Error(), Valid: true}
+	if err := s.db.CreateFetchLog(context.Background(), attempt); err != nil {
+		return fmt.Errorf("error: could not record fetch attempt -> %w", err)
+	}
+	return recordFetchError(s, log, feed, err)
+}
+
+// defaultRetryAfter is the shortest time a rate limited or unavailable feed is
+// left alone when the server doesn't say how long to wait.
+const defaultRetryAfter = 30 * time.Minute
+
+// recordFetchError stores a failed fetch against the feed so it rotates to the
+// back of the queue instead of stopping aggregation. Failing feeds back off
+// exponentially, feeds answering 410 Gone are marked dead and never fetched
+// again, and 429 or 503 wait at least as long as Retry-After asks.
+func recordFetchError(s *state, log *slog.Logger, feed database.GetNextFeedToFetchRow, fetchErr error) error {
+	now := time.Now()
+	lastError := sql.NullString{String: fetchErr.Error(), Valid: true}
+	wait := backoffDelay(feed.ConsecutiveFailures+1, maxBackoff(s.cfg))
+	var status sql.NullInt32
+	var statusErr *statusError
+	if errors.As(fetchErr, &statusErr) {
+		status = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+		switch statusErr.StatusCode {
+		case http.StatusGone:
+			dead := database.MarkFeedDeadParams{
+				UpdatedAt:  now,
+				DeadAt:     sql.NullTime{Time: now, Valid: true},
+				LastStatus: status,
+				LastError:  lastError,
+				ID:         feed.ID,
+			}
+			if err := s.db.MarkFeedDead(context.Background(), dead); err != nil {
+				return fmt.Errorf("error: could not mark feed as dead -> %w", err)
+			}
+			log.Warn("feed is gone, it will no longer be fetched", "status", statusErr.StatusCode)
+			return nil
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			retryAfter := statusErr.RetryAfter
+			if retryAfter <= 0 {
+				retryAfter = defaultRetryAfter
+			}
+			wait = max(wait, retryAfter)
+		}
+	}
+	nextFetch := sql.NullTime{Time: now.Add(wait), Valid: true}
+	failed := database.RecordFeedErrorParams{
+		UpdatedAt:     now,
+		LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+		LastStatus:    status,
+		LastError:     lastError,
+		NextFetchAt:   nextFetch,
+		ID:            feed.ID,
+	}
+	if err := s.db.RecordFeedError(context.Background(), failed); err != nil {
+		return fmt.Errorf("error: could not record feed fetch error -> %w", err)
+	}
+	log.Error("feed fetch failed",
+		"error", fetchErr,
+		"failures", feed.ConsecutiveFailures+1,
+		"next_attempt", nextFetch.Time.Format(time.DateTime),
+	)
+	return nil
+}
+
+// permanentRedirectThreshold is how many fetches in a row must be permanently
+// redirected to the same url before the feed's url is updated to match.
+const permanentRedirectThreshold = 3
+
+func trackRedirect(fetched *database.MarkFeedFetchedParams, feed database.GetNextFeedToFetchRow, info fetchInfo) {
+	if info.FinalURL == feed.Url {
+		return
+	}
+	fetched.FinalUrl = sql.NullString{String: info.FinalURL, Valid: true}
+	if !info.Permanent {
+		return
+	}
+	fetched.RedirectUrl = sql.NullString{String: info.FinalURL, Valid: true}
+	fetched.RedirectCount = 1
+	if feed.RedirectUrl.Valid && feed.RedirectUrl.String == info.FinalURL {
+		fetched.RedirectCount = feed.RedirectCount + 1
+	}
+}
+
+// moveFeedURL points the feed at newURL, keeping its old url in the history,
+// in one transaction. If another feed is already at newURL the move is rolled
+// back and the redirect count reset, so it is only retried after the redirect
+// has been seen consistently again. A feed with credentials is not moved to
+// another host, its secrets were only meant for the host they were set for.
+func moveFeedURL(s *state, log *slog.Logger, feed database.GetNextFeedToFetchRow, creds feedCredentials, newURL string) error {
+	if !creds.empty() && !strings.EqualFold(feedHost(newURL), creds.Host) {
+		log.Warn("feed with credentials moved permanently to another host, add it again at the new url to follow it", "new_url", newURL)
+		return resetRedirect(s, feed)
+	}
+	tx, err := s.sqlDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("error: could not begin transaction -> %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+	history := database.CreateFeedURLHistoryParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		FeedID:    feed.ID,
+		Url:       feed.Url,
+	}
+	if err := qtx.CreateFeedURLHistory(context.Background(), history); err != nil {
+		return fmt.Errorf("error: could not record previous feed url -> %w", err)
+	}
+	moved := database.UpdateFeedURLParams{
+		Url:       newURL,
+		UpdatedAt: time.Now(),
+		ID:        feed.ID,
+	}
+	if err := qtx.UpdateFeedURL(context.Background(), moved); err != nil {
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+			return fmt.Errorf("error: could not update moved feed url -> %w", err)
 		}
+		tx.Rollback()
+		log.Warn("feed moved permanently but a feed already exists at the new url", "new_url", newURL)
+		return resetRedirect(s, feed)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error: could not commit moved feed url -> %w", err)
+	}
+	log.Info("feed moved permanently, url updated", "new_url", newURL)
+	return nil
+}
+
+func resetRedirect(s *state, feed database.GetNextFeedToFetchRow) error {
+	reset := database.ResetFeedRedirectParams{
+		UpdatedAt: time.Now(),
+		ID:        feed.ID,
+	}
+	if err := s.db.ResetFeedRedirect(context.Background(), reset); err != nil {
+		return fmt.Errorf("error: could not reset feed redirect count -> %w", err)
 	}
-	fmt.Printf(`
-░█▀▀░█░░░█▀▀░█▀▀░█▀█░▀█▀░█▀█░█▀▀░░░░░░░░░
-░▀▀█░█░░░█▀▀░█▀▀░█▀▀░░█░░█░█░█░█░░░░░░░░░
-░▀▀▀░▀▀▀░▀▀▀░▀▀▀░▀░░░▀▀▀░▀░▀░▀▀▀░▀░░▀░░▀░` + "\n")
-	fmt.Printf("»»»» Awaiting next fetch round...\n")
 	return nil
 }
 
@@ -87,21 +317,17 @@ func parsePubDate(pubdate string) (time.Time, error) {
 	}
 	for _, layout := range layouts {
 		if t, err := time.Parse(layout, s); err == nil {
-			return t, nil
+			// Saved without a zone like every other time, so local.
+			return t.Local(), nil
 		}
 	}
 	return time.Now(), fmt.Errorf("error: pubDate not in a recognizable format or is null\n PublishedAt set to current time")
 }
 
-func handleInsertErr(err error) {
-	var pqErr *pq.Error
-	if errors.As(err, &pqErr) {
-		if pqErr.Code == "23505" && pqErr.Constraint == "posts_url_key" {
-			fmt.Printf("skipping: post already recorded in previous fetch ✓\n")
-			return
-		}
-		fmt.Printf("db error (%s/%s): %s\n", pqErr.Code, pqErr.Constraint, pqErr.Message)
-		return
+func feedHost(feedurl string) string {
+	u, err := url.Parse(feedurl)
+	if err != nil {
+		return ""
 	}
-	fmt.Printf("insert error: %v\n", err)
+	return u.Hostname()
 }
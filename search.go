@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+const defaultSearchLimit = 10
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("search")
+	all := fs.Bool("all", false, "search every post rather than only followed feeds")
+	limit := fs.Int("limit", defaultSearchLimit, "most results to show")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return fmt.Errorf("error: nothing to search for, use 'search \"exact phrase\" word -excluded prefix*'")
+	}
+	terms, prefixes, err := splitSearch(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	search := database.SearchPostsParams{
+		Terms:    terms,
+		Prefixes: prefixes,
+		AllPosts: *all,
+		UserID:   user.ID,
+		MaxPosts: int32(*limit),
+	}
+	posts, err := s.db.SearchPosts(context.Background(), search)
+	if err != nil {
+		return fmt.Errorf("error: could not search posts -> %w", err)
+	}
+	ids := make([]uuid.UUID, 0, len(posts))
+	for i := range posts {
+		ids = append(ids, posts[i].ID)
+	}
+	handles, err := postHandles(s, user, ids)
+	if err != nil {
+		return err
+	}
+	if s.output != outputTable {
+		records := make([]searchRecord, 0, len(posts))
+		for i := range posts {
+			records = append(records, newSearchRecord(posts[i], handles[posts[i].ID]))
+		}
+		return writeRecords(s.output, records)
+	}
+	fmt.Printf(`
+░█▀▀░█▀▀░█▀█░█▀▄░█▀▀░█░█
+░▀▀█░█▀▀░█▀█░█▀▄░█░░░█▀█
+░▀▀▀░▀▀▀░▀░▀░▀░▀░▀▀▀░▀░▀` + "\n\n")
+	if len(posts) == 0 {
+		fmt.Printf("»»»» no posts match '%v'\n\n", strings.Join(args, " "))
+	}
+	for i := range posts {
+		fmt.Println(strings.Repeat("◈", 34))
+		fmt.Printf("»»»» [%v] %v\n", handles[posts[i].ID], posts[i].Title)
+		fmt.Printf("»»» %v, %v\n", posts[i].FeedName, posts[i].PublishedAt.Time.Format(time.DateOnly))
+		fmt.Printf("»» %v\n", posts[i].Url)
+		fmt.Printf("» %v\n\n", normalizeSpaces(posts[i].Snippet))
+	}
+	return nil
+}
+
+// splitSearch separates words ending in '*' from the rest of a query. The rest
+// goes to websearch_to_tsquery for quoted phrases, 'or' and '-' exclusions,
+// which has no prefix syntax, so prefix words become a to_tsquery string of
+// 'word:*' terms that must all match. The two are ANDed together, so 'or' is
+// refused alongside prefix words rather than quietly becoming AND.
+func splitSearch(query string) (terms, prefixes string, err error) {
+	var rest, prefix []string
+	inPhrase, hasOr := false, false
+	for _, word := range strings.Fields(query) {
+		if strings.Count(word, `"`)%2 == 1 {
+			inPhrase = !inPhrase
+		}
+		if !inPhrase && strings.EqualFold(word, "or") {
+			hasOr = true
+		}
+		stem, ok := strings.CutSuffix(word, "*")
+		if !ok || inPhrase || strings.ContainsAny(word, `"-`) {
+			rest = append(rest, word)
+			continue
+		}
+		stem = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, stem)
+		if stem != "" {
+			prefix = append(prefix, stem+":*")
+		}
+	}
+	if hasOr && len(prefix) > 0 {
+		return "", "", fmt.Errorf("error: 'or' can't be used with prefix words ending in '*', search for each alternative separately")
+	}
+	return strings.Join(rest, " "), strings.Join(prefix, " & "), nil
+}
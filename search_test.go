@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestSplitSearch(t *testing.T) {
+	tests := []struct {
+		query        string
+		wantTerms    string
+		wantPrefixes string
+		wantErr      bool
+	}{
+		{"postgres", "postgres", "", false},
+		{"kube*", "", "kube:*", false},
+		{`"connection pool" postgres -mysql`, `"connection pool" postgres -mysql`, "", false},
+		{"kube* docker* -swarm", "-swarm", "kube:* & docker:*", false},
+		{"rust or go*", "", "", true},
+		{"rust OR go* -java", "", "", true},
+		{"rust or go", "rust or go", "", false},
+		{`"rust or go" web*`, `"rust or go"`, "web:*", false},
+		{`"a phrase* inside"`, `"a phrase* inside"`, "", false},
+		{`"quoted*"`, `"quoted*"`, "", false},
+		{"-excluded*", "-excluded*", "", false},
+		{"c++*", "", "c:*", false},
+		{"it's*", "", "its:*", false},
+		{"*", "", "", false},
+		{"  spaced   out*  ", "spaced", "out:*", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		terms, prefixes, err := splitSearch(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitSearch(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if terms != tt.wantTerms || prefixes != tt.wantPrefixes {
+			t.Errorf("splitSearch(%q) = %q, %q, want %q, %q", tt.query, terms, prefixes, tt.wantTerms, tt.wantPrefixes)
+		}
+	}
+}
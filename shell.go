@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+const shellHistoryFile = ".gator_history"
+
+// secretCommands take credentials on the command line, so their lines are
+// left out of the shell history.
+var secretCommands = map[string]bool{
+	"setauth":   true,
+	"setheader": true,
+}
+
+// handlerShell runs commands from an interactive prompt against the same state
+// and database connection until 'exit' or ctrl-d. Logging in from the shell
+// only switches user for the session and leaves .gatorconfig.json alone.
+func (c *commands) handlerShell(s *state, cmd command) error {
+	if s.session {
+		return fmt.Errorf("error: already in a gator shell")
+	}
+	history := shellHistoryPath()
+	protectHistory(history)
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 shellPrompt(s),
+		HistoryFile:            history,
+		DisableAutoSaveHistory: true,
+		HistorySearchFold:      true,
+		AutoComplete:           &shellCompleter{s: s, c: c},
+		InterruptPrompt:        "^C",
+		EOFPrompt:              "exit",
+	})
+	if err != nil {
+		return fmt.Errorf("error: could not start shell -> %w", err)
+	}
+	defer rl.Close()
+	s.session = true
+	defer func() { s.session = false }()
+	output := s.output
+	defer func() { s.output = output }()
+	fmt.Printf("»»»» gator shell, 'help' lists commands, 'exit' or ctrl-d leaves\n")
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error: could not read input -> %w", err)
+		}
+		if keepInHistory(line) {
+			rl.SaveHistory(line)
+			protectHistory(history)
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			continue
+		}
+		s.output, args, err = extractOutputFlag(args, output)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		name := strings.ToLower(args[0])
+		switch name {
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Printf("»»»» %v\n", strings.Join(c.names(), " "))
+			continue
+		}
+		if _, ok := c.commands[name]; !ok {
+			fmt.Printf("error: unknown command '%v'\n", name)
+			continue
+		}
+		if err := c.run(s, command{name: name, args: args[1:]}); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		rl.SetPrompt(shellPrompt(s))
+	}
+}
+
+func shellPrompt(s *state) string {
+	if s.cfg.UserName == "" {
+		return "gator » "
+	}
+	return fmt.Sprintf("gator (%v) » ", s.cfg.UserName)
+}
+
+func shellHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, shellHistoryFile)
+}
+
+// protectHistory makes the history file readable by its owner only, creating
+// it if need be. readline creates and rewrites it with the default umask.
+func protectHistory(path string) {
+	if path == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return
+	}
+	f.Close()
+	os.Chmod(path, 0o600)
+}
+
+// keepInHistory reports whether line should be saved to the history, leaving
+// out blank lines and any line naming a command given credentials, wherever
+// it appears so a mistyped flag before it can't sneak it in.
+func keepInHistory(line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		args = strings.Fields(line)
+	}
+	for _, arg := range args {
+		if secretCommands[strings.ToLower(arg)] {
+			return false
+		}
+	}
+	return len(args) > 0
+}
+
+// names lists the registered commands in order for help and completion.
+func (c *commands) names() []string {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// splitArgs breaks a shell line into arguments the way a unix shell would for
+// the simple cases, honouring single and double quotes and backslash escapes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("error: unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("error: line ends with a backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// quoteArg quotes a completion so it splits back into a single argument.
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t'\"\\") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+// shellCompleter completes command names for the first word and feed names
+// and urls after that.
+type shellCompleter struct {
+	s *state
+	c *commands
+}
+
+func (sc *shellCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	before := string(line[:pos])
+	start := lastArgStart(before)
+	typed := before[start:]
+	var candidates []string
+	if strings.TrimSpace(before[:start]) == "" {
+		candidates = append(sc.c.names(), "exit", "help")
+	} else {
+		feeds, err := sc.s.db.GetFeeds(context.Background())
+		if err != nil {
+			return nil, 0
+		}
+		for i := range feeds {
+			candidates = append(candidates, quoteArg(feeds[i].Name), quoteArg(feeds[i].Url))
+		}
+	}
+	var completions [][]rune
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, typed) {
+			completions = append(completions, []rune(candidate[len(typed):]+" "))
+		}
+	}
+	return completions, len([]rune(typed))
+}
+
+// lastArgStart is the byte offset where the argument being typed begins,
+// skipping spaces inside an open quote.
+func lastArgStart(line string) int {
+	start := 0
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			start = i + 1
+		}
+	}
+	return start
+}
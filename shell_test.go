@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"   ", nil, false},
+		{"browse 10", []string{"browse", "10"}, false},
+		{"  browse \t 10  ", []string{"browse", "10"}, false},
+		{`addfeed "Hacker News" https://news.ycombinator.com/rss`, []string{"addfeed", "Hacker News", "https://news.ycombinator.com/rss"}, false},
+		{`login 'bob smith'`, []string{"login", "bob smith"}, false},
+		{`search "it's here"`, []string{"search", "it's here"}, false},
+		{`search 'say "hi"'`, []string{"search", `say "hi"`}, false},
+		{`login bob\ smith`, []string{"login", "bob smith"}, false},
+		{`search "a \"quoted\" word"`, []string{"search", `a "quoted" word`}, false},
+		{`search 'back\slash'`, []string{"search", `back\slash`}, false},
+		{`login ""`, []string{"login", ""}, false},
+		{`browse --match=""`, []string{"browse", "--match="}, false},
+		{`login "bob`, nil, true},
+		{`login 'bob`, nil, true},
+		{`login bob\`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitArgs(%q) error = %v, want error %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	for _, arg := range []string{"hn", "Hacker News", "", `it's`, `say "hi"`, `back\slash`} {
+		got, err := splitArgs("follow " + quoteArg(arg))
+		if err != nil || len(got) != 2 || got[1] != arg {
+			t.Errorf("quoteArg(%q) = %v splits back to %q, %v", arg, quoteArg(arg), got, err)
+		}
+	}
+}
+
+func TestLastArgStart(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"", 0},
+		{"bro", 0},
+		{"follow ", 7},
+		{"follow hack", 7},
+		{`follow "Hacker N`, 7},
+		{`follow "Hacker News" ht`, 21},
+		{"unfollow  x", 10},
+	}
+	for _, tt := range tests {
+		if got := lastArgStart(tt.line); got != tt.want {
+			t.Errorf("lastArgStart(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestKeepInHistory(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"browse 5", true},
+		{`search "setauth docs"`, true},
+		{"", false},
+		{"   ", false},
+		{"setauth https://example.com/feed basic alice hunter2", false},
+		{"SetAuth https://example.com/feed bearer token", false},
+		{"setheader https://example.com/feed Cookie session=abc", false},
+		{"-o json setauth https://example.com/feed basic alice hunter2", false},
+		{"-o bogus setheader https://example.com/feed Cookie x", false},
+		{`setauth https://example.com/feed basic alice "unterminated`, false},
+	}
+	for _, tt := range tests {
+		if got := keepInHistory(tt.line); got != tt.want {
+			t.Errorf("keepInHistory(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestProtectHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{"created", false},
+		{"existing file narrowed", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), shellHistoryFile)
+			if tt.existing {
+				if err := os.WriteFile(path, []byte("browse 5\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			protectHistory(path)
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm(); mode != 0o600 {
+				t.Errorf("history file mode = %v, want %v", mode, os.FileMode(0o600))
+			}
+			if tt.existing && info.Size() == 0 {
+				t.Error("protectHistory() emptied the existing history")
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jdfincher/gator/internal/database"
+)
+
+// aggLockKey is the Postgres advisory lock held by the running aggregator,
+// "gator" in ascii.
+const aggLockKey int64 = 0x6761746f72
+
+// singletonLock is an advisory lock held on its own connection, Postgres ties
+// the lock to the session so it is released if the connection drops.
+type singletonLock struct {
+	conn *sql.Conn
+	db   *database.Queries
+}
+
+// acquireSingleton takes the aggregator lock. With mode "exit" it fails if
+// another agg holds it, with "wait" it blocks as a standby until it is free.
+func acquireSingleton(s *state, mode string) (*singletonLock, error) {
+	if mode != "exit" && mode != "wait" {
+		return nil, fmt.Errorf("error: unknown singleton mode '%v', use exit or wait", mode)
+	}
+	conn, err := s.sqlDB.Conn(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error: could not open connection for singleton lock -> %w", err)
+	}
+	lock := &singletonLock{conn: conn, db: database.New(conn)}
+	ok, err := lock.db.TryAdvisoryLock(context.Background(), aggLockKey)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error: could not take singleton lock -> %w", err)
+	}
+	if ok {
+		return lock, nil
+	}
+	if mode == "exit" {
+		conn.Close()
+		return nil, fmt.Errorf("error: another agg is already running against this database")
+	}
+	s.log.Info("another agg is running against this database, waiting as standby")
+	if err := lock.db.AdvisoryLock(context.Background(), aggLockKey); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error: could not take singleton lock -> %w", err)
+	}
+	s.log.Info("singleton lock acquired, taking over")
+	return lock, nil
+}
+
+// check confirms the lock's connection is still alive, a dropped connection
+// means the lock is gone and another agg may already have taken over.
+func (l *singletonLock) check() error {
+	if err := l.conn.PingContext(context.Background()); err != nil {
+		return fmt.Errorf("error: lost singleton lock connection -> %w", err)
+	}
+	return nil
+}
+
+func (l *singletonLock) release() {
+	l.db.AdvisoryUnlock(context.Background(), aggLockKey)
+	l.conn.Close()
+}
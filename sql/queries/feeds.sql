@@ -14,17 +14,68 @@ RETURNING *;
 SELECT * FROM feeds;
 
 -- name: GetFeedID :one
-SELECT id FROM feeds WHERE url = $1;
+SELECT id FROM (
+  SELECT feeds.id, 0 AS rank, feeds.created_at FROM feeds WHERE feeds.url = $1
+  UNION ALL
+  SELECT feed_url_history.feed_id, 1 AS rank, feed_url_history.created_at FROM feed_url_history WHERE feed_url_history.url = $1
+) AS matches
+ORDER BY rank, created_at DESC
+LIMIT 1;
+
+-- name: GetFeedIDForUser :one
+SELECT id FROM feeds WHERE url = $1 AND user_id = $2;
+
+-- name: SetFeedAuth :execrows
+UPDATE feeds
+SET auth_type = $1, auth_username = $2, auth_secret = $3, updated_at = $4
+WHERE url = $5 AND user_id = $6;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds 
-SET updated_at = $1, last_fetched_at = $2
-WHERE id = $3;
+SET updated_at = $1, last_fetched_at = $2, final_url = $3, redirect_url = $4, redirect_count = $5,
+    last_status = $6, last_error = NULL, next_fetch_at = NULL, consecutive_failures = 0,
+    bytes_transferred = bytes_transferred + $7, bytes_decoded = bytes_decoded + $8
+WHERE id = $9;
+
+-- name: RecordFeedError :exec
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $2, last_status = $3, last_error = $4, next_fetch_at = $5,
+    consecutive_failures = consecutive_failures + 1
+WHERE id = $6;
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET updated_at = $1, dead_at = $2, last_status = $3, last_error = $4
+WHERE id = $5;
 
 -- name: GetNextFeedToFetch :one
-SELECT id, url, updated_at, last_fetched_at
+SELECT id, url, updated_at, last_fetched_at, redirect_url, redirect_count, consecutive_failures,
+  auth_type, auth_username, auth_secret
 FROM feeds
+WHERE dead_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp)
 ORDER BY last_fetched_at NULLS FIRST, updated_at ASC
 LIMIT 1;
 
+-- name: CountDueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE dead_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp);
+
+-- name: CreateFeedURLHistory :exec
+INSERT INTO feed_url_history (id, created_at, feed_id, url)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (feed_id, url) DO NOTHING;
+
+-- name: ResetFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = $1
+WHERE id = $2;
 
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2, final_url = NULL, redirect_url = NULL, redirect_count = 0
+WHERE id = $3;
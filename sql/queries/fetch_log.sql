@@ -0,0 +1,24 @@
+-- name: CreateFetchLog :exec
+INSERT INTO fetch_log (id, feed_id, started_at, duration_ms, http_status, bytes, items_seen, new_posts, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+);
+
+-- name: GetFetchStats :many
+SELECT
+  feed_id,
+  COUNT(*) AS attempts,
+  COUNT(*) FILTER (WHERE error IS NULL) AS successes,
+  AVG(duration_ms)::float8 AS avg_duration_ms,
+  MAX(started_at)::timestamp AS last_attempt_at
+FROM fetch_log
+WHERE started_at >= $1
+GROUP BY feed_id;
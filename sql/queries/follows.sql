@@ -19,7 +19,13 @@ INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follow.user_id = users.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_id, feeds.url, feeds.name AS feed_name FROM feed_follows
+SELECT feed_id, feeds.url, feeds.name AS feed_name,
+  (SELECT COUNT(*) FROM posts
+   WHERE posts.feed_id = feed_follows.feed_id
+     AND NOT EXISTS (
+       SELECT 1 FROM post_reads WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+     )) AS unread
+FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 WHERE feed_follows.user_id = $1;
 
@@ -0,0 +1,26 @@
+-- name: EnsurePostHandles :many
+WITH missing AS (
+  SELECT wanted.post_id, wanted.ord
+  FROM unnest(sqlc.arg(post_ids)::uuid[]) WITH ORDINALITY AS wanted(post_id, ord)
+  WHERE NOT EXISTS (
+    SELECT 1 FROM post_handles WHERE post_handles.user_id = sqlc.arg(user_id) AND post_handles.post_id = wanted.post_id
+  )
+), inserted AS (
+  INSERT INTO post_handles (user_id, handle, post_id)
+  SELECT sqlc.arg(user_id),
+    (SELECT COALESCE(MAX(handle), 0) FROM post_handles WHERE user_id = sqlc.arg(user_id)) + ROW_NUMBER() OVER (ORDER BY missing.ord),
+    missing.post_id
+  FROM missing
+  ON CONFLICT (user_id, post_id) DO NOTHING
+  RETURNING post_id, handle
+)
+SELECT inserted.post_id, inserted.handle FROM inserted
+UNION ALL
+SELECT post_handles.post_id, post_handles.handle FROM post_handles
+WHERE post_handles.user_id = sqlc.arg(user_id) AND post_handles.post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: GetPostIDByHandle :one
+SELECT post_id FROM post_handles WHERE user_id = $1 AND handle = $2;
+
+-- name: LockPostHandles :exec
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(user_id)::uuid::text));
@@ -0,0 +1,18 @@
+-- name: SetFeedHeader :exec
+INSERT INTO feed_headers (id, created_at, updated_at, feed_id, name, value)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (feed_id, name) DO UPDATE
+SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at;
+
+-- name: DeleteFeedHeader :execrows
+DELETE FROM feed_headers WHERE feed_id = $1 AND name = $2;
+
+-- name: GetFeedHeaders :many
+SELECT * FROM feed_headers WHERE feed_id = $1 ORDER BY name;
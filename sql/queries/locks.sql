@@ -0,0 +1,8 @@
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock(sqlc.arg(key)::bigint);
+
+-- name: AdvisoryLock :exec
+SELECT pg_advisory_lock(sqlc.arg(key)::bigint);
+
+-- name: AdvisoryUnlock :exec
+SELECT pg_advisory_unlock(sqlc.arg(key)::bigint);
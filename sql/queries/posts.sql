@@ -1,21 +1,62 @@
--- name: CreatePost :one
+-- name: CreatePosts :many
 INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-) RETURNING *;
+SELECT item.id, sqlc.arg(now)::timestamp, sqlc.arg(now)::timestamp, item.title, item.url, item.description, item.published_at, sqlc.arg(feed_id)::uuid
+FROM unnest(
+    sqlc.arg(ids)::uuid[],
+    sqlc.arg(titles)::text[],
+    sqlc.arg(urls)::text[],
+    sqlc.arg(descriptions)::text[],
+    sqlc.arg(published_at)::timestamp[]
+) AS item(id, title, url, description, published_at)
+ON CONFLICT (url) DO NOTHING
+RETURNING url;
 
 -- name: GetPostsForUser :many
-SELECT posts.*
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, post_reads.read_at, COALESCE(posts.published_at, posts.updated_at)::timestamp AS sort_at
 FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY COALESCE(posts.published_at, posts.updated_at) DESC
-LIMIT $2;
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND (sqlc.arg(include_read)::boolean OR post_reads.read_at IS NULL)
+  AND (sqlc.narg(after_at)::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.updated_at), posts.id) < (sqlc.narg(after_at), sqlc.narg(after_id)::uuid))
+  AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+  AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.updated_at) >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.updated_at) < sqlc.narg(until))
+  AND (sqlc.narg(match)::text IS NULL
+    OR posts.title ILIKE '%' || sqlc.narg(match) || '%' ESCAPE '\' OR posts.description ILIKE '%' || sqlc.narg(match) || '%' ESCAPE '\')
+ORDER BY COALESCE(posts.published_at, posts.updated_at) DESC, posts.id DESC
+LIMIT sqlc.arg(max_posts) OFFSET sqlc.arg(skip_posts);
 
+-- name: FindPosts :many
+SELECT id, title, url, description, published_at, content FROM posts
+WHERE (url = sqlc.arg(ref) OR id::text LIKE sqlc.narg(id_prefix)::text || '%')
+  AND (feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg(user_id))
+    OR id IN (SELECT post_id FROM post_stars WHERE post_stars.user_id = sqlc.arg(user_id)))
+ORDER BY id
+LIMIT 2;
+
+
+-- name: GetLastPostTimes :many
+SELECT feed_id, MAX(created_at)::timestamp AS last_post_at
+FROM posts
+GROUP BY feed_id;
+
+-- name: SearchPosts :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name,
+  ts_rank(posts.search, search.query)::real AS rank,
+  ts_headline('english', posts.title || ' ' || posts.description || ' ' || COALESCE(posts.content, ''), search.query,
+    'StartSel=**, StopSel=**, MaxFragments=2, MinWords=8, MaxWords=20, FragmentDelimiter=" … "')::text AS snippet
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+CROSS JOIN (
+  SELECT websearch_to_tsquery('english', sqlc.arg(terms)::text) && to_tsquery('english', sqlc.arg(prefixes)::text) AS query
+) AS search
+WHERE posts.search @@ search.query
+  AND (sqlc.arg(all_posts)::boolean OR EXISTS (
+    SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+  ))
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.updated_at) DESC
+LIMIT sqlc.arg(max_posts);
+
+-- name: SetPostContent :exec
+UPDATE posts SET content = $1, content_fetched_at = $2 WHERE id = $3;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD final_url TEXT,
+ADD redirect_url TEXT,
+ADD redirect_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE feed_url_history(
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  feed_id UUID NOT NULL,
+  url TEXT NOT NULL,
+  CONSTRAINT fk_feeds FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+  CONSTRAINT feed_url_history_feed_url UNIQUE (feed_id, url)
+);
+
+-- +goose Down
+DROP TABLE feed_url_history;
+
+ALTER TABLE feeds
+DROP COLUMN final_url,
+DROP COLUMN redirect_url,
+DROP COLUMN redirect_count;
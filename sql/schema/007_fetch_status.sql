@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD dead_at TIMESTAMP,
+ADD next_fetch_at TIMESTAMP,
+ADD last_status INTEGER,
+ADD last_error TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN dead_at,
+DROP COLUMN next_fetch_at,
+DROP COLUMN last_status,
+DROP COLUMN last_error;
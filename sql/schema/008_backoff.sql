@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD consecutive_failures INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN consecutive_failures;
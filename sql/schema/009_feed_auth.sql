@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD auth_type TEXT,
+ADD auth_username TEXT,
+ADD auth_secret TEXT;
+
+CREATE TABLE feed_headers(
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  feed_id UUID NOT NULL,
+  name TEXT NOT NULL,
+  value TEXT NOT NULL,
+  CONSTRAINT fk_feeds FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+  CONSTRAINT feed_headers_feed_name UNIQUE (feed_id, name)
+);
+
+-- +goose Down
+DROP TABLE feed_headers;
+
+ALTER TABLE feeds
+DROP COLUMN auth_type,
+DROP COLUMN auth_username,
+DROP COLUMN auth_secret;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD bytes_transferred BIGINT NOT NULL DEFAULT 0,
+ADD bytes_decoded BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN bytes_transferred,
+DROP COLUMN bytes_decoded;
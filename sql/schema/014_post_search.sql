@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', title), 'A') ||
+  setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX posts_search ON posts USING GIN (search);
+
+-- +goose Down
+DROP INDEX posts_search;
+ALTER TABLE posts DROP COLUMN search;
@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT;
+ALTER TABLE posts ADD COLUMN content_fetched_at TIMESTAMP;
+
+ALTER TABLE posts DROP COLUMN search;
+ALTER TABLE posts ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', title), 'A') ||
+  setweight(to_tsvector('english', description), 'B') ||
+  setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX posts_search ON posts USING GIN (search);
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN search;
+ALTER TABLE posts ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', title), 'A') ||
+  setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX posts_search ON posts USING GIN (search);
+
+ALTER TABLE posts DROP COLUMN content_fetched_at;
+ALTER TABLE posts DROP COLUMN content;
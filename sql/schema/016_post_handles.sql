@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE post_handles(
+  user_id UUID NOT NULL,
+  handle INTEGER NOT NULL,
+  post_id UUID NOT NULL,
+  PRIMARY KEY (user_id, handle),
+  UNIQUE (user_id, post_id),
+  CONSTRAINT fk_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT fk_posts FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_handles;
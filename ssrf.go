@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/jdfincher/gator/internal/config"
+)
+
+// blockedPrefixes are ranges refused on top of the loopback, private and
+// link-local checks built into netip.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved
+}
+
+// ssrfGuard dials on behalf of the fetcher when block_private_addresses is on.
+// Every connection, including those made to follow a redirect, resolves the
+// host itself and refuses private, loopback and link-local addresses unless
+// the host or address is on the allowlist. The checked address is the one
+// dialed, so a second DNS answer can't swap in an internal address. Requests
+// sent through a proxy dial the proxy, so proxyGuard checks their own host.
+type ssrfGuard struct {
+	dialer       *net.Dialer
+	resolver     *net.Resolver
+	allowedHosts map[string]bool
+	allowedNets  []netip.Prefix
+}
+
+func newSSRFGuard(fc config.FetchConfig, dialer *net.Dialer) (*ssrfGuard, error) {
+	g := &ssrfGuard{
+		dialer:       dialer,
+		resolver:     net.DefaultResolver,
+		allowedHosts: make(map[string]bool),
+	}
+	for _, entry := range fc.AllowHosts {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			g.allowedNets = append(g.allowedNets, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			g.allowedNets = append(g.allowedNets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		if entry == "" || strings.ContainsAny(entry, "/:") {
+			return nil, fmt.Errorf("error: fetch allow_hosts entry %q is not a host name, address or cidr", entry)
+		}
+		g.allowedHosts[entry] = true
+	}
+	return g, nil
+}
+
+func (g *ssrfGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if g.allowedHosts[strings.ToLower(host)] {
+		return g.dialer.DialContext(ctx, network, addr)
+	}
+	ips, err := g.checkHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var dialErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// checkHost resolves host and refuses it if any of its addresses is blocked,
+// otherwise returning them. A host on the allowlist isn't resolved.
+func (g *ssrfGuard) checkHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	if g.allowedHosts[strings.ToLower(host)] {
+		return nil, nil
+	}
+	ips, err := g.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if !g.allowed(ip.Unmap()) {
+			return nil, fmt.Errorf("error: refusing to connect to %v (%v), private addresses are blocked by fetch block_private_addresses", host, ip.Unmap())
+		}
+	}
+	return ips, nil
+}
+
+func (g *ssrfGuard) allowed(ip netip.Addr) bool {
+	for _, prefix := range g.allowedNets {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// proxyGuard checks the host of every request sent through a proxy, redirects
+// included, as the guarded dial then only sees the proxy's address.
+type proxyGuard struct {
+	next  http.RoundTripper
+	proxy func(*http.Request) (*url.URL, error)
+	guard *ssrfGuard
+}
+
+func (t *proxyGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxyURL, err := t.proxy(req)
+	if err == nil && proxyURL != nil {
+		_, err = t.guard.checkHost(req.Context(), req.URL.Hostname())
+	}
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
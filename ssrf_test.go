@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jdfincher/gator/internal/config"
+)
+
+func TestSSRFGuardAllowed(t *testing.T) {
+	guard, err := newSSRFGuard(config.FetchConfig{AllowHosts: []string{"10.1.0.0/16", "192.168.1.5", "feeds.internal"}}, &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::6810:85e5", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"10.1.2.3", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+	}
+	for _, tt := range tests {
+		if got := guard.allowed(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("allowed(%v) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestNewSSRFGuardRejectsBadEntries(t *testing.T) {
+	for _, entry := range []string{"", "http://feeds.internal", "feeds.internal:8080", "10.0.0.0/33"} {
+		if _, err := newSSRFGuard(config.FetchConfig{AllowHosts: []string{entry}}, &net.Dialer{}); err == nil {
+			t.Errorf("newSSRFGuard(%q) accepted an invalid allow_hosts entry", entry)
+		}
+	}
+}
+
+func TestSSRFGuardDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	tests := []struct {
+		name    string
+		allow   []string
+		host    string
+		wantErr bool
+	}{
+		{"loopback blocked", nil, "127.0.0.1", true},
+		{"loopback name blocked", nil, "localhost", true},
+		{"allowed by address", []string{"127.0.0.1"}, "127.0.0.1", false},
+		{"allowed by name", []string{"localhost"}, "localhost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newSSRFGuard(config.FetchConfig{AllowHosts: tt.allow}, &net.Dialer{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := guard.DialContext(context.Background(), "tcp", net.JoinHostPort(tt.host, port))
+			if err == nil {
+				conn.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DialContext(%v) error = %v, want error %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProxyGuard(t *testing.T) {
+	proxyURL, _ := url.Parse("http://127.0.0.1:3128")
+	tests := []struct {
+		name    string
+		target  string
+		proxy   bool
+		wantErr bool
+	}{
+		{"metadata address through proxy", "http://169.254.169.254/latest/", true, true},
+		{"loopback through proxy", "http://127.0.0.1:5432/", true, true},
+		{"loopback name through proxy", "http://localhost/", true, true},
+		{"public address through proxy", "http://93.184.216.34/feed", true, false},
+		{"allowed host through proxy", "http://10.1.2.3/feed", true, false},
+		{"no proxy left to the dial guard", "http://127.0.0.1/", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newSSRFGuard(config.FetchConfig{AllowHosts: []string{"10.1.0.0/16"}}, &net.Dialer{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sent := false
+			transport := &proxyGuard{
+				guard: guard,
+				proxy: func(*http.Request) (*url.URL, error) {
+					if tt.proxy {
+						return proxyURL, nil
+					}
+					return nil, nil
+				},
+				next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+					sent = true
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+				}),
+			}
+			req, err := http.NewRequest(http.MethodGet, tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = transport.RoundTrip(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RoundTrip(%v) error = %v, want error %v", tt.target, err, tt.wantErr)
+			}
+			if sent == tt.wantErr {
+				t.Errorf("RoundTrip(%v) sent the request = %v, want %v", tt.target, sent, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetcherChecksHostBehindProxy(t *testing.T) {
+	var hits atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte("internal secrets"))
+	}))
+	defer proxy.Close()
+	proxyHost, _, _ := net.SplitHostPort(proxy.Listener.Addr().String())
+	cfg := &config.Config{Fetch: config.FetchConfig{
+		Proxy:                 proxy.URL,
+		BlockPrivateAddresses: true,
+		AllowHosts:            []string{proxyHost},
+		HostMinDelay:          config.Duration{Duration: time.Millisecond},
+	}}
+	f, err := newFetcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.fetchPage(context.Background(), "http://169.254.169.254/latest/meta-data/"); err == nil {
+		t.Error("fetchPage() through a proxy reached a blocked address")
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("proxy received %d requests for a blocked address, want 0", n)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("error: no post provided, use 'star n' with the number shown by browse")
+	}
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	star := database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		StarredAt: time.Now(),
+	}
+	if err := s.db.StarPost(context.Background(), star); err != nil {
+		return fmt.Errorf("error: could not star post -> %w", err)
+	}
+	fmt.Printf("»»»» starred: %v\n", post.Title)
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("error: no post provided, use 'unstar n' with the number shown by starred")
+	}
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	unstar := database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	n, err := s.db.UnstarPost(context.Background(), unstar)
+	if err != nil {
+		return fmt.Errorf("error: could not unstar post -> %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error: post '%v' is not starred", post.Title)
+	}
+	fmt.Printf("»»»» unstarred: %v\n", post.Title)
+	return nil
+}
+
+// handlerStarred lists starred posts whether or not their feed is still
+// followed.
+func handlerStarred(s *state, cmd command, user database.User) error {
+	posts, err := s.db.GetStarredPosts(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not fetch starred posts -> %w", err)
+	}
+	ids := make([]uuid.UUID, 0, len(posts))
+	for i := range posts {
+		ids = append(ids, posts[i].ID)
+	}
+	handles, err := postHandles(s, user, ids)
+	if err != nil {
+		return err
+	}
+	if s.output != outputTable {
+		records := make([]starredRecord, 0, len(posts))
+		for i := range posts {
+			records = append(records, newStarredRecord(posts[i], handles[posts[i].ID]))
+		}
+		return writeRecords(s.output, records)
+	}
+	fmt.Printf(`
+░█▀▀░▀█▀░█▀█░█▀▄░█▀▄░█▀▀░█▀▄
+░▀▀█░░█░░█▀█░█▀▄░█▀▄░█▀▀░█░█
+░▀▀▀░░▀░░▀░▀░▀░▀░▀░▀░▀▀▀░▀▀░` + "\n\n")
+	if len(posts) == 0 {
+		fmt.Printf("»»»» nothing starred yet, use 'star n' to keep a post\n\n")
+	}
+	for i := range posts {
+		fmt.Println(strings.Repeat("◈", 34))
+		fmt.Printf("»»»» [%v] %v\n", handles[posts[i].ID], posts[i].Title)
+		fmt.Printf("»»» %v, starred %v\n", posts[i].FeedName, posts[i].StarredAt.Format(time.DateTime))
+		fmt.Printf("»» %v\n", posts[i].Url)
+		fmt.Printf("» %v\n\n", posts[i].Description)
+	}
+	return nil
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/jdfincher/gator/internal/database"
+)
+
+const (
+	defaultTUIRefresh = 10 * time.Second
+	// tuiMaxPosts is how many of the newest posts the post pane loads.
+	tuiMaxPosts   = 500
+	feedPaneWidth = 28
+)
+
+type tuiPane int
+
+const (
+	feedPane tuiPane = iota
+	postPane
+	readPane
+)
+
+var (
+	paneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	focusStyle   = paneStyle.BorderForeground(lipgloss.Color("212"))
+	selectStyle  = lipgloss.NewStyle().Reverse(true)
+	readStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	titleStyle   = lipgloss.NewStyle().Bold(true)
+	statusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	tagRE        = regexp.MustCompile(`<[^>]*>`)
+	tuiHelpLines = "tab pane · j/k move · enter open · r read · s star · u unread only · R refresh · q quit"
+)
+
+// tuiModel is the state of the full screen reader. Database work happens in
+// commands that report back with the messages below so the screen never
+// blocks, and a tick reloads both lists to pick up posts added by agg.
+type tuiModel struct {
+	s       *state
+	user    database.User
+	refresh time.Duration
+
+	width, height int
+	focus         tuiPane
+
+	feeds      []database.GetFeedFollowsForUserRow
+	feedIdx    int // 0 is every followed feed, feeds[i] is at i+1
+	posts      []database.GetPostsForUserRow
+	handles    map[uuid.UUID]int32
+	postIdx    int
+	starred    map[uuid.UUID]bool
+	unreadOnly bool
+
+	reading    *database.GetPostsForUserRow
+	readScroll int
+
+	status string
+	err    error
+}
+
+type feedsLoadedMsg struct {
+	feeds   []database.GetFeedFollowsForUserRow
+	starred map[uuid.UUID]bool
+}
+
+type postsLoadedMsg struct {
+	feedID  uuid.NullUUID
+	posts   []database.GetPostsForUserRow
+	handles map[uuid.UUID]int32
+}
+
+type postChangedMsg struct{ status string }
+
+type tuiErrMsg struct{ err error }
+
+type tuiTickMsg time.Time
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("tui")
+	refresh := fs.Duration("refresh", defaultTUIRefresh, "how often to check for new posts")
+	if _, err := parseFlags(fs, cmd.args); err != nil {
+		return err
+	}
+	m := tuiModel{
+		s:       s,
+		user:    user,
+		refresh: *refresh,
+		starred: map[uuid.UUID]bool{},
+	}
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("error: terminal ui failed -> %w", err)
+	}
+	return nil
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.loadFeeds(), m.loadPosts(), m.tick())
+}
+
+func (m tuiModel) tick() tea.Cmd {
+	return tea.Tick(m.refresh, func(t time.Time) tea.Msg { return tuiTickMsg(t) })
+}
+
+func (m tuiModel) selectedFeed() uuid.NullUUID {
+	if m.feedIdx == 0 || m.feedIdx > len(m.feeds) {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: m.feeds[m.feedIdx-1].FeedID, Valid: true}
+}
+
+func (m tuiModel) loadFeeds() tea.Cmd {
+	return func() tea.Msg {
+		feeds, err := m.s.db.GetFeedFollowsForUser(context.Background(), m.user.ID)
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("error: could not fetch followed feeds -> %w", err)}
+		}
+		stars, err := m.s.db.GetStarredPosts(context.Background(), m.user.ID)
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("error: could not fetch starred posts -> %w", err)}
+		}
+		starred := make(map[uuid.UUID]bool, len(stars))
+		for i := range stars {
+			starred[stars[i].ID] = true
+		}
+		return feedsLoadedMsg{feeds: feeds, starred: starred}
+	}
+}
+
+func (m tuiModel) loadPosts() tea.Cmd {
+	params := database.GetPostsForUserParams{
+		UserID:      m.user.ID,
+		IncludeRead: !m.unreadOnly,
+		FeedID:      m.selectedFeed(),
+		MaxPosts:    tuiMaxPosts,
+	}
+	return func() tea.Msg {
+		posts, err := m.s.db.GetPostsForUser(context.Background(), params)
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("error: could not fetch posts -> %w", err)}
+		}
+		ids := make([]uuid.UUID, 0, len(posts))
+		for i := range posts {
+			ids = append(ids, posts[i].ID)
+		}
+		handles, err := postHandles(m.s, m.user, ids)
+		if err != nil {
+			return tuiErrMsg{err}
+		}
+		return postsLoadedMsg{feedID: params.FeedID, posts: posts, handles: handles}
+	}
+}
+
+// setRead marks a post read or unread for the user.
+func (m tuiModel) setRead(post database.GetPostsForUserRow, read bool) tea.Cmd {
+	return func() tea.Msg {
+		if read {
+			params := database.MarkPostReadParams{UserID: m.user.ID, PostID: post.ID, ReadAt: time.Now()}
+			if err := m.s.db.MarkPostRead(context.Background(), params); err != nil {
+				return tuiErrMsg{fmt.Errorf("error: could not mark post as read -> %w", err)}
+			}
+			return postChangedMsg{status: "marked read: " + post.Title}
+		}
+		params := database.MarkPostUnreadParams{UserID: m.user.ID, PostID: post.ID}
+		if _, err := m.s.db.MarkPostUnread(context.Background(), params); err != nil {
+			return tuiErrMsg{fmt.Errorf("error: could not mark post as unread -> %w", err)}
+		}
+		return postChangedMsg{status: "marked unread: " + post.Title}
+	}
+}
+
+func (m tuiModel) setStar(post database.GetPostsForUserRow, star bool) tea.Cmd {
+	return func() tea.Msg {
+		if star {
+			params := database.StarPostParams{UserID: m.user.ID, PostID: post.ID, StarredAt: time.Now()}
+			if err := m.s.db.StarPost(context.Background(), params); err != nil {
+				return tuiErrMsg{fmt.Errorf("error: could not star post -> %w", err)}
+			}
+			return postChangedMsg{status: "starred: " + post.Title}
+		}
+		params := database.UnstarPostParams{UserID: m.user.ID, PostID: post.ID}
+		if _, err := m.s.db.UnstarPost(context.Background(), params); err != nil {
+			return tuiErrMsg{fmt.Errorf("error: could not unstar post -> %w", err)}
+		}
+		return postChangedMsg{status: "unstarred: " + post.Title}
+	}
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case feedsLoadedMsg:
+		m.feeds = msg.feeds
+		m.starred = msg.starred
+		m.feedIdx = min(m.feedIdx, len(m.feeds))
+	case postsLoadedMsg:
+		if msg.feedID != m.selectedFeed() {
+			return m, nil
+		}
+		m.keepSelection(msg.posts)
+		m.handles = msg.handles
+	case postChangedMsg:
+		m.status = msg.status
+		return m, tea.Batch(m.loadFeeds(), m.loadPosts())
+	case tuiErrMsg:
+		m.err = msg.err
+	case tuiTickMsg:
+		return m, tea.Batch(m.loadFeeds(), m.loadPosts(), m.tick())
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// keepSelection swaps in a reloaded post list, keeping the cursor on the same
+// post when it is still there so new posts arriving don't move it.
+func (m *tuiModel) keepSelection(posts []database.GetPostsForUserRow) {
+	var selected uuid.UUID
+	if m.postIdx < len(m.posts) {
+		selected = m.posts[m.postIdx].ID
+	}
+	m.posts = posts
+	for i := range posts {
+		if posts[i].ID == selected {
+			m.postIdx = i
+			return
+		}
+	}
+	m.postIdx = max(0, min(m.postIdx, len(posts)-1))
+}
+
+func (m tuiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.err = nil
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % 3
+		return m, nil
+	case "shift+tab":
+		m.focus = (m.focus + 2) % 3
+		return m, nil
+	case "R":
+		m.status = "refreshing"
+		return m, tea.Batch(m.loadFeeds(), m.loadPosts())
+	case "u":
+		m.unreadOnly = !m.unreadOnly
+		m.postIdx = 0
+		return m, m.loadPosts()
+	}
+	switch m.focus {
+	case feedPane:
+		return m.feedKey(msg)
+	case postPane:
+		return m.postKey(msg)
+	default:
+		return m.readKey(msg)
+	}
+}
+
+func (m tuiModel) feedKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	prev := m.feedIdx
+	switch msg.String() {
+	case "up", "k":
+		m.feedIdx = max(0, m.feedIdx-1)
+	case "down", "j":
+		m.feedIdx = min(len(m.feeds), m.feedIdx+1)
+	case "enter", "right", "l":
+		m.focus = postPane
+	}
+	if m.feedIdx != prev {
+		m.posts = nil
+		m.postIdx = 0
+		return m, m.loadPosts()
+	}
+	return m, nil
+}
+
+func (m tuiModel) postKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.postIdx = max(0, m.postIdx-1)
+	case "down", "j":
+		m.postIdx = min(max(0, len(m.posts)-1), m.postIdx+1)
+	case "left", "h":
+		m.focus = feedPane
+	}
+	if len(m.posts) == 0 {
+		return m, nil
+	}
+	post := m.posts[m.postIdx]
+	switch msg.String() {
+	case "enter", "right", "l":
+		m.reading = &post
+		m.readScroll = 0
+		m.focus = readPane
+		if !post.ReadAt.Valid {
+			return m, m.setRead(post, true)
+		}
+	case "r":
+		return m, m.setRead(post, !post.ReadAt.Valid)
+	case "s":
+		return m, m.setStar(post, !m.starred[post.ID])
+	}
+	return m, nil
+}
+
+func (m tuiModel) readKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.readScroll = max(0, m.readScroll-1)
+	case "down", "j":
+		m.readScroll++
+	case "pgup", "b":
+		m.readScroll = max(0, m.readScroll-m.paneHeight())
+	case "pgdown", " ":
+		m.readScroll += m.paneHeight()
+	case "left", "h", "esc":
+		m.focus = postPane
+	case "s":
+		if m.reading != nil {
+			return m, m.setStar(*m.reading, !m.starred[m.reading.ID])
+		}
+	}
+	_, readWidth := m.paneWidths()
+	m.readScroll = min(m.readScroll, max(0, len(m.readText(readWidth))-m.paneHeight()))
+	return m, nil
+}
+
+// paneWidths splits the space left by the feed pane between the post and
+// reading panes, each pane's border taking two more cells.
+func (m tuiModel) paneWidths() (post, read int) {
+	post = max(20, (m.width-feedPaneWidth)*2/5)
+	read = max(20, m.width-feedPaneWidth-post-6)
+	return post, read
+}
+
+// paneHeight is the number of lines inside a pane, leaving room for the
+// borders and the status line.
+func (m tuiModel) paneHeight() int {
+	return max(1, m.height-3)
+}
+
+func (m tuiModel) View() string {
+	if m.width == 0 {
+		return "loading…"
+	}
+	h := m.paneHeight()
+	postWidth, readWidth := m.paneWidths()
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.pane(feedPane, feedPaneWidth, h, m.feedLines(feedPaneWidth, h)),
+		m.pane(postPane, postWidth, h, m.postLines(postWidth, h)),
+		m.pane(readPane, readWidth, h, m.readLines(readWidth, h)),
+	)
+	status := statusStyle.Render(truncate(tuiHelpLines, m.width))
+	if m.err != nil {
+		status = errorStyle.Render(truncate(m.err.Error(), m.width))
+	} else if m.status != "" {
+		status = statusStyle.Render(truncate(m.status+" · "+tuiHelpLines, m.width))
+	}
+	return panes + "\n" + status
+}
+
+func (m tuiModel) pane(p tuiPane, width, height int, lines []string) string {
+	style := paneStyle
+	if m.focus == p {
+		style = focusStyle
+	}
+	return style.Width(width).Height(height).Render(strings.Join(lines, "\n"))
+}
+
+func (m tuiModel) feedLines(width, height int) []string {
+	var unread int64
+	for i := range m.feeds {
+		unread += m.feeds[i].Unread
+	}
+	entries := []string{fmt.Sprintf("All feeds (%d)", unread)}
+	for i := range m.feeds {
+		entries = append(entries, fmt.Sprintf("%v (%d)", m.feeds[i].FeedName, m.feeds[i].Unread))
+	}
+	var lines []string
+	start := scrollStart(m.feedIdx, height)
+	for i := start; i < len(entries) && i < start+height; i++ {
+		line := truncate(entries[i], width)
+		if i == m.feedIdx {
+			line = selectStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (m tuiModel) postLines(width, height int) []string {
+	if len(m.posts) == 0 {
+		if m.unreadOnly {
+			return []string{"nothing unread, u shows read posts"}
+		}
+		return []string{"no posts"}
+	}
+	var lines []string
+	start := scrollStart(m.postIdx, height)
+	for i := start; i < len(m.posts) && i < start+height; i++ {
+		mark := "  "
+		if m.starred[m.posts[i].ID] {
+			mark = "★ "
+		} else if !m.posts[i].ReadAt.Valid {
+			mark = "• "
+		}
+		line := truncate(fmt.Sprintf("%v%v %v", mark, m.handles[m.posts[i].ID], m.posts[i].Title), width)
+		switch {
+		case i == m.postIdx:
+			line = selectStyle.Render(line)
+		case m.posts[i].ReadAt.Valid:
+			line = readStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (m tuiModel) readLines(width, height int) []string {
+	if m.reading == nil {
+		return []string{"select a post and press enter to read it"}
+	}
+	lines := m.readText(width)
+	start := min(m.readScroll, len(lines))
+	return lines[start:min(len(lines), start+height)]
+}
+
+// readText lays out the post being read wrapped to width.
+func (m tuiModel) readText(width int) []string {
+	if m.reading == nil {
+		return nil
+	}
+	post := m.reading
+	header := []string{
+		titleStyle.Width(width).Render(post.Title),
+		readStyle.Render(truncate(fmt.Sprintf("%v · %v", m.handles[post.ID], post.SortAt.Format(time.DateTime)), width)),
+		readStyle.Render(truncate(post.Url, width)),
+		"",
+	}
+	body := normalizeSpaces(tagRE.ReplaceAllString(post.Description, " "))
+	text := strings.Join(header, "\n") + "\n" + lipgloss.NewStyle().Width(width).Render(body)
+	return strings.Split(text, "\n")
+}
+
+// scrollStart is the first line to draw so the selected line stays visible.
+func scrollStart(selected, height int) int {
+	if selected < height {
+		return 0
+	}
+	return selected - height + 1
+}
+
+// truncate shortens s to fit in width terminal cells.
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
+}